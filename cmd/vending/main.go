@@ -1,13 +1,17 @@
 package main
 
 import (
+	"os"
+
 	"github.com/alevinval/vendor-go/pkg/cmd"
 	"github.com/alevinval/vendor-go/pkg/vending"
 )
 
 func main() {
-	cmd.NewCobraCommand(
-		cmd.WithCommandName("vending"),
-		cmd.WithPreset(&vending.DefaultPreset{}),
-	).Execute()
+	os.Exit(cmd.Execute(
+		cmd.NewCobraCommand(
+			cmd.WithCommandName("vending"),
+			cmd.WithPreset(&vending.DefaultPreset{}),
+		),
+	))
 }
@@ -1,11 +1,15 @@
 package cache
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/alevinval/vendor-go/internal/git"
 	"github.com/alevinval/vendor-go/internal/lock"
@@ -62,21 +66,24 @@ func (c *Cache) Reset() error {
 }
 
 // Lock acquires the global cache lock. This can be used to ensure only one agent
-// is manipulating the cache and its contents.
-func (c *Cache) Lock() (*lock.Lock, error) {
+// is manipulating the cache and its contents. Waiting for the lock stops when
+// the context is done, or after the timeout, when it is not zero.
+func (c *Cache) Lock(ctx context.Context, timeout time.Duration) (*lock.Lock, error) {
 	if err := c.Init(); err != nil {
 		return nil, fmt.Errorf("cannot initialize paths: %w", err)
 	}
 
-	lock := lock.New(c.lockPath()).WithWarn(
+	cacheLock := lock.New(c.lockPath()).WithWarn(
 		color.RedString(
 			"cannot acquire cache lock, are you running multiple instances in parallel?",
 		),
-	)
-	if err := lock.Acquire(); err != nil {
+	).WithTimeout(timeout)
+	if err := cacheLock.AcquireContext(ctx); errors.Is(err, lock.ErrTimeout) {
+		return nil, fmt.Errorf("%w %q: %w", vending.ErrLockTimeout, c.lockPath(), err)
+	} else if err != nil {
 		return nil, fmt.Errorf("cannot acquire lock %q: %w", c.lockPath(), err)
 	}
-	return lock, nil
+	return cacheLock, nil
 }
 
 // GetRepository returns git.Repository from the cache.
@@ -127,9 +134,21 @@ func (c *Cache) getRepositoryPath(dep *vending.Dependency) string {
 	)
 }
 
+// getSha1 hashes the normalized URL of the dependency, so every dependency
+// on the same repository shares its clone and lock, whatever its name, and a
+// dependency whose URL changes gets a new clone.
 func getSha1(dep *vending.Dependency) string {
 	sha := sha1.New()
-	sha.Write([]byte(dep.URL))
+	sha.Write([]byte(normalizeURL(dep.URL)))
 	data := sha.Sum(nil)
 	return hex.EncodeToString(data)
 }
+
+// normalizeURL returns the URL in a form that is the same for the spellings of
+// a repository URL that refer to the same repository: URLs are compared without
+// case, like the lockfile does, and without trailing slashes or ".git" suffix.
+func normalizeURL(url string) string {
+	url = strings.ToLower(strings.TrimSpace(url))
+	url = strings.TrimRight(url, "/")
+	return strings.TrimSuffix(url, ".git")
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"io/fs"
 	"os"
 	"testing"
@@ -63,7 +64,7 @@ func TestCache_Lock(t *testing.T) {
 
 	sut := New(testCachePath)
 
-	lock, err := sut.Lock()
+	lock, err := sut.Lock(context.Background(), 0)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, lock)
@@ -93,6 +94,28 @@ func TestCache_GetRepositoryLockPath(t *testing.T) {
 	)
 }
 
+func TestCache_GetRepositoryPath_SharedByURL(t *testing.T) {
+	one := vending.NewDependency("some-url", "some-branch")
+	one.Name = "one"
+	two := vending.NewDependency("SOME-URL.git/", "other-branch")
+	two.Name = "two"
+	sut := New(testCachePath)
+
+	assert.Equal(t, sut.getRepositoryPath(one), sut.getRepositoryPath(two))
+	assert.Equal(t, sut.getRepositoryLockPath(one), sut.getRepositoryLockPath(two))
+}
+
+func TestCache_GetRepositoryPath_SameNameOtherURL(t *testing.T) {
+	one := vending.NewDependency("some-url", "some-branch")
+	one.Name = "same"
+	two := vending.NewDependency("other-url", "some-branch")
+	two.Name = "same"
+	sut := New(testCachePath)
+
+	assert.NotEqual(t, sut.getRepositoryPath(one), sut.getRepositoryPath(two))
+	assert.NotEqual(t, sut.getRepositoryLockPath(one), sut.getRepositoryLockPath(two))
+}
+
 type fsMock struct {
 	mock.Mock
 }
@@ -1,9 +1,13 @@
 package git
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/alevinval/vendor-go/pkg/log"
+	"github.com/alevinval/vendor-go/pkg/vending"
 	"github.com/fatih/color"
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -25,43 +29,50 @@ func (g Git) GetCurrentCommit(path string) (string, error) {
 	return head.Hash().String(), nil
 }
 
-func (g Git) OpenOrClone(url, branch, path string) error {
+// Exists returns whether there is a repository at path.
+func (g Git) Exists(path string) bool {
 	_, err := git.PlainOpen(path)
-	if err == nil {
-		return nil
-	}
-
-	return g.Clone(url, branch, path)
+	return err == nil
 }
 
-func (g Git) Clone(url, branch, path string) error {
+// Clone checks out the default branch of the remote, the reference that has
+// to be vendored, be it a branch, a tag or a commit, is checked out later on
+// with Reset. A clone that fails, or is interrupted, is removed so the next
+// attempt starts from scratch.
+// Progress messages of the remote are written to progress, when not nil.
+func (g Git) Clone(ctx context.Context, url, path string, progress io.Writer) error {
 	log.S().Infof(
 		"cloning %s...",
 		color.CyanString(url),
 	)
 	cloneOpts := &git.CloneOptions{
-		URL:           url,
-		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		URL:      url,
+		Tags:     git.AllTags,
+		Progress: progress,
 	}
-	_, err := git.PlainClone(path, false, cloneOpts)
+	_, err := git.PlainCloneContext(ctx, path, false, cloneOpts)
 	if err != nil {
-		return fmt.Errorf("cannot clone %s: %w", url, err)
+		os.RemoveAll(path)
+		return fmt.Errorf("cannot clone %s: %w", url, remoteErr(ctx, err))
 	}
 
 	return nil
 }
 
-func (g Git) Fetch(path string) error {
+// Fetch updates the branches and tags of the repository, progress messages of
+// the remote are written to progress, when not nil.
+func (g Git) Fetch(ctx context.Context, path string, progress io.Writer) error {
 	repo, err := git.PlainOpen(path)
 	if err != nil {
 		return gitOpenErr(err)
 	}
 
 	fetchOpts := &git.FetchOptions{
-		Force: true,
-		Tags:  git.AllTags,
+		Force:    true,
+		Tags:     git.AllTags,
+		Progress: progress,
 	}
-	err = repo.Fetch(fetchOpts)
+	err = repo.FetchContext(ctx, fetchOpts)
 	switch err {
 	case git.NoErrAlreadyUpToDate:
 		return nil
@@ -69,10 +80,37 @@ func (g Git) Fetch(path string) error {
 		return nil
 	}
 
-	return fmt.Errorf("cannot git fetch: %w", err)
+	return fmt.Errorf("cannot git fetch: %w", remoteErr(ctx, err))
+}
+
+// Tags returns the short names of all the tags of the repository.
+func (g Git) Tags(path string) ([]string, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return nil, gitOpenErr(err)
+	}
+
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list tags: %w", err)
+	}
+
+	tags := []string{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		tags = append(tags, ref.Name().Short())
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot iterate tags: %w", err)
+	}
+
+	return tags, nil
 }
 
-func (g Git) Reset(path, refname string) error {
+// Reset checks out the revision, discarding any change of the worktree. The
+// context is checked before the checkout starts, go-git cannot interrupt it,
+// but a checkout left halfway is fixed by the next Reset.
+func (g Git) Reset(ctx context.Context, path, refname string) error {
 	repo, err := git.PlainOpen(path)
 	if err != nil {
 		return gitOpenErr(err)
@@ -97,6 +135,10 @@ func (g Git) Reset(path, refname string) error {
 		return fmt.Errorf("cannot resolve revision %q: %w", refname, err)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	wt, err := repo.Worktree()
 	if err != nil {
 		return fmt.Errorf("cannot get worktree: %w", err)
@@ -122,6 +164,15 @@ func (g Git) Reset(path, refname string) error {
 	return nil
 }
 
+// remoteErr marks the errors of talking to the remote as network errors,
+// unless they are caused by the context being done.
+func remoteErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", vending.ErrNetwork, err)
+}
+
 func gitOpenErr(err error) error {
 	return fmt.Errorf("cannot open: %w", err)
 }
@@ -1,10 +1,12 @@
 package git
 
 import (
+	"context"
 	"io/fs"
 	"path/filepath"
 
 	"github.com/alevinval/vendor-go/internal/lock"
+	"github.com/alevinval/vendor-go/pkg/event"
 	"github.com/alevinval/vendor-go/pkg/vending"
 )
 
@@ -13,6 +15,7 @@ type Repository struct {
 	git  *Git
 	lock *lock.Lock
 	path string
+	sink event.Sink
 }
 
 func NewRepository(path string, lock *lock.Lock, dep *vending.Dependency) *Repository {
@@ -21,23 +24,41 @@ func NewRepository(path string, lock *lock.Lock, dep *vending.Dependency) *Repos
 		git:  &Git{},
 		lock: lock,
 		path: path,
+		sink: event.Discard,
 	}
 }
 
+// WithSink configures the sink that receives the events of cloning and
+// fetching the repository.
+func (r *Repository) WithSink(sink event.Sink) *Repository {
+	r.sink = sink
+	return r
+}
+
 func (r *Repository) Path() string {
 	return r.path
 }
 
-func (r *Repository) OpenOrClone() error {
-	return r.git.OpenOrClone(r.dep.URL, r.dep.Branch, r.Path())
+func (r *Repository) OpenOrClone(ctx context.Context) error {
+	if r.git.Exists(r.Path()) {
+		return nil
+	}
+
+	r.sink.Emit(event.CloneStarted{Dependency: r.dep.ID(), URL: r.dep.URL})
+	return r.git.Clone(ctx, r.dep.URL, r.Path(), event.ProgressWriter(r.sink, r.dep.ID()))
+}
+
+func (r *Repository) Fetch(ctx context.Context) error {
+	r.sink.Emit(event.FetchStarted{Dependency: r.dep.ID(), URL: r.dep.URL})
+	return r.git.Fetch(ctx, r.Path(), event.ProgressWriter(r.sink, r.dep.ID()))
 }
 
-func (r *Repository) Fetch() error {
-	return r.git.Fetch(r.Path())
+func (r *Repository) Reset(ctx context.Context, refname string) error {
+	return r.git.Reset(ctx, r.Path(), refname)
 }
 
-func (r *Repository) Reset(refname string) error {
-	return r.git.Reset(r.Path(), refname)
+func (r *Repository) Tags() ([]string, error) {
+	return r.git.Tags(r.Path())
 }
 
 func (r *Repository) GetCurrentCommit() (string, error) {
@@ -48,6 +69,6 @@ func (r *Repository) WalkDir(fn fs.WalkDirFunc) error {
 	return filepath.WalkDir(r.Path(), fn)
 }
 
-func (r *Repository) Lock() (*lock.Lock, error) {
-	return r.lock, r.lock.Acquire()
+func (r *Repository) Lock(ctx context.Context) (*lock.Lock, error) {
+	return r.lock, r.lock.AcquireContext(ctx)
 }
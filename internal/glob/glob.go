@@ -0,0 +1,104 @@
+// Package glob implements path-segment aware pattern matching for the paths
+// that are targeted or ignored when vendoring a dependency.
+//
+// Patterns are split by "/" and every segment is matched with path.Match,
+// so "*", "?" and character classes never cross a segment boundary. A segment
+// consisting only of "**" matches zero or more segments. A pattern also
+// matches everything beneath a path it matches, this way "doc" selects
+// "doc/index.md" but not "docs/index.md" nor "doc.go".
+package glob
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const doubleStar = "**"
+
+// HasMeta reports whether the pattern contains any of the special characters
+// recognised by Match.
+func HasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// Validate returns an error when the pattern is malformed.
+func Validate(pattern string) error {
+	for _, seg := range split(pattern) {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether the pattern matches the whole name.
+func Match(pattern, name string) bool {
+	return matchSegs(split(pattern), split(name))
+}
+
+// MatchTree reports whether the pattern matches the name, or any of the parent
+// directories of the name.
+func MatchTree(pattern, name string) bool {
+	pat := split(pattern)
+	segs := split(name)
+	for i := 1; i <= len(segs); i++ {
+		if matchSegs(pat, segs[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchWithin reports whether the pattern could match the directory dir, or
+// any path beneath it. It is used to decide when a directory walk can be
+// pruned.
+func MatchWithin(pattern, dir string) bool {
+	pat := split(pattern)
+	for _, seg := range split(dir) {
+		if len(pat) == 0 || pat[0] == doubleStar {
+			return true
+		}
+		if ok, _ := path.Match(pat[0], seg); !ok {
+			return false
+		}
+		pat = pat[1:]
+	}
+	return true
+}
+
+func matchSegs(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == doubleStar {
+			for len(pat) > 0 && pat[0] == doubleStar {
+				pat = pat[1:]
+			}
+			if len(pat) == 0 {
+				return true
+			}
+			for i := range segs {
+				if matchSegs(pat, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+func split(name string) []string {
+	name = path.Clean(filepath.ToSlash(name))
+	if name == "." || name == "/" {
+		return []string{}
+	}
+	return strings.Split(strings.Trim(name, "/"), "/")
+}
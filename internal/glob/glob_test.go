@@ -0,0 +1,62 @@
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		expected      bool
+	}{
+		{"doc", "doc", true},
+		{"doc", "docs", false},
+		{"doc", "doc.go", false},
+		{"doc/", "doc", true},
+		{"./doc", "doc", true},
+		{"api/*/v1/*.proto", "api/ledger/v1/ledger.proto", true},
+		{"api/*/v1/*.proto", "api/ledger/v2/ledger.proto", false},
+		{"api/*/v1/*.proto", "api/a/b/v1/ledger.proto", false},
+		{"**/testdata/**", "testdata", true},
+		{"**/testdata/**", "pkg/testdata/file.txt", true},
+		{"**/testdata/**", "pkg/testdata2/file.txt", false},
+		{"**/*.proto", "a/b/c.proto", true},
+		{"**/*.proto", "c.proto", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/y/c", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, Match(c.pattern, c.name), "Match(%q, %q)", c.pattern, c.name)
+	}
+}
+
+func TestMatchTree(t *testing.T) {
+	assert.True(t, MatchTree("doc", "doc/index.md"))
+	assert.True(t, MatchTree("doc", "doc"))
+	assert.False(t, MatchTree("doc", "docs/index.md"))
+	assert.False(t, MatchTree("doc", "doc.go"))
+	assert.True(t, MatchTree("api/*/v1", "api/ledger/v1/ledger.proto"))
+	assert.True(t, MatchTree("**/testdata", "a/b/testdata/c/d.txt"))
+	assert.False(t, MatchTree("", "a"))
+}
+
+func TestMatchWithin(t *testing.T) {
+	assert.True(t, MatchWithin("api/*/v1/*.proto", "api"))
+	assert.True(t, MatchWithin("api/*/v1/*.proto", "api/ledger"))
+	assert.True(t, MatchWithin("api/*/v1/*.proto", "api/ledger/v1"))
+	assert.False(t, MatchWithin("api/*/v1/*.proto", "api/ledger/v2"))
+	assert.False(t, MatchWithin("api/*/v1/*.proto", "docs"))
+	assert.True(t, MatchWithin("doc", "doc/nested"))
+	assert.False(t, MatchWithin("doc", "docs"))
+	assert.True(t, MatchWithin("a/**/b", "a/x/y/z"))
+	assert.True(t, MatchWithin("**/testdata", "anything"))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("api/*/v1/**/*.proto"))
+	assert.Error(t, Validate("api/[a-"))
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/alevinval/vendor-go/pkg/log"
+	"github.com/alevinval/vendor-go/pkg/vending"
 )
 
 type targetCollector struct {
@@ -17,59 +18,68 @@ type target struct {
 	src    string
 	srcRel string
 	dst    string
+	dstRel string
 }
 
 func (tc *targetCollector) add(t target) {
 	tc.targets = append(tc.targets, t)
 }
 
-func (tc *targetCollector) copyAll() error {
+// copyAll copies every target, and returns the digest of each copied file
+// keyed by its slash separated path relative to the vendor directory. The
+// copied function is called with that path after each file is copied.
+func (tc *targetCollector) copyAll(copied func(path string)) (map[string]string, error) {
+	files := map[string]string{}
 	for _, target := range tc.targets {
-		err := target.copy()
+		digest, err := target.copy()
 		if err != nil {
-			return fmt.Errorf("cannot copy: %w", err)
+			return nil, fmt.Errorf("cannot copy: %w", err)
 		}
+		path := filepath.ToSlash(target.dstRel)
+		files[path] = digest
+		copied(path)
 	}
-	return nil
+	return files, nil
 }
 
-func (t *target) copy() error {
+func (t *target) copy() (string, error) {
 	log.S().Debugf("  [copy] ../%s -> %s", t.srcRel, t.dst)
 
 	dstDir := filepath.Dir(t.dst)
 	err := os.MkdirAll(dstDir, os.ModePerm)
 	if err != nil {
-		return fmt.Errorf("cannot create dstDir %q: %w", dstDir, err)
+		return "", fmt.Errorf("cannot create dstDir %q: %w", dstDir, err)
 	}
-	err = copyFile(t.src, t.dst)
+	digest, err := copyFile(t.src, t.dst)
 	if err != nil {
-		return fmt.Errorf("cannot copyFile: %w", err)
+		return "", fmt.Errorf("cannot copyFile: %w", err)
 	}
-	return nil
+	return digest, nil
 }
 
-func copyFile(src, dst string) error {
+func copyFile(src, dst string) (string, error) {
 	in, err := os.Open(src)
 	if err != nil {
-		return fmt.Errorf("cannot open %q: %w", src, err)
+		return "", fmt.Errorf("cannot open %q: %w", src, err)
 	}
 	defer in.Close()
 
 	out, err := os.Create(dst)
 	if err != nil {
-		return fmt.Errorf("cannot create %q: %w", dst, err)
+		return "", fmt.Errorf("cannot create %q: %w", dst, err)
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, in)
+	hash, sum := vending.NewDigest()
+	_, err = io.Copy(io.MultiWriter(out, hash), in)
 	if err != nil {
-		return fmt.Errorf("cannot copy %q => %q: %w", src, dst, err)
+		return "", fmt.Errorf("cannot copy %q => %q: %w", src, dst, err)
 	}
 
 	err = out.Close()
 	if err != nil {
-		return fmt.Errorf("cannot close %q: %w", dst, err)
+		return "", fmt.Errorf("cannot close %q: %w", dst, err)
 	}
 
-	return nil
+	return sum(), nil
 }
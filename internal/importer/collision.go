@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/alevinval/vendor-go/pkg/vending"
+)
+
+// Output holds the files that a dependency vendors, keyed by their path
+// relative to the vendor directory, as returned by Import.
+type Output struct {
+	Dependency *vending.Dependency
+	Files      map[string]string
+}
+
+// Collision is a path that several dependencies, with the same priority,
+// vendor with different contents.
+type Collision struct {
+	Path         string
+	Dependencies []*vending.Dependency
+}
+
+func (c *Collision) Error() string {
+	ids := []string{}
+	for _, dep := range c.Dependencies {
+		ids = append(ids, dep.ID())
+	}
+	return fmt.Sprintf("%s: %s is vendored by %s", vending.ErrOutputCollision, c.Path, strings.Join(ids, " and "))
+}
+
+func (c *Collision) Unwrap() error {
+	return vending.ErrOutputCollision
+}
+
+// ResolveCollisions finds the paths that several outputs vendor with
+// different contents. The path is kept by the output of the dependency with
+// the highest priority, and removed from the Files of the others. Outputs
+// with the same contents as the winner keep the path too, since it does not
+// matter which of them writes it.
+//
+// Paths where the highest priority is shared by outputs with different
+// contents cannot be resolved, they are returned sorted by path and their
+// outputs are left untouched.
+func ResolveCollisions(outputs []*Output) []*Collision {
+	claims := map[string][]*Output{}
+	for _, output := range outputs {
+		for path := range output.Files {
+			claims[path] = append(claims[path], output)
+		}
+	}
+
+	collisions := []*Collision{}
+	for _, path := range slices.Sorted(maps.Keys(claims)) {
+		claimants := claims[path]
+		if len(claimants) < 2 {
+			continue
+		}
+
+		top := claimants[0]
+		for _, output := range claimants[1:] {
+			if output.Dependency.Priority > top.Dependency.Priority {
+				top = output
+			}
+		}
+
+		collision := &Collision{Path: path}
+		for _, output := range claimants {
+			if output.Dependency.Priority == top.Dependency.Priority && output.Files[path] != top.Files[path] {
+				collision.Dependencies = append(collision.Dependencies, output.Dependency)
+			}
+		}
+		if len(collision.Dependencies) > 0 {
+			collision.Dependencies = append([]*vending.Dependency{top.Dependency}, collision.Dependencies...)
+			collisions = append(collisions, collision)
+			continue
+		}
+
+		for _, output := range claimants {
+			if output.Files[path] != top.Files[path] {
+				delete(output.Files, path)
+			}
+		}
+	}
+	return collisions
+}
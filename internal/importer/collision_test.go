@@ -0,0 +1,55 @@
+package importer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/stretchr/testify/assert"
+)
+
+func newOutput(name string, priority int, files map[string]string) *Output {
+	dep := vending.NewDependency("some-url", "some-branch")
+	dep.Name = name
+	dep.Priority = priority
+	return &Output{Dependency: dep, Files: files}
+}
+
+func TestResolveCollisions_SamePriority_Fails(t *testing.T) {
+	a := newOutput("a", 0, map[string]string{"README.md": "digest-a", "a.proto": "digest-a"})
+	b := newOutput("b", 0, map[string]string{"README.md": "digest-b", "b.proto": "digest-b"})
+
+	collisions := ResolveCollisions([]*Output{a, b})
+
+	assert.Len(t, collisions, 1)
+	assert.Equal(t, "README.md", collisions[0].Path)
+	assert.Equal(t, []*vending.Dependency{a.Dependency, b.Dependency}, collisions[0].Dependencies)
+	assert.EqualError(t, collisions[0], "output path collision: README.md is vendored by a and b")
+	assert.True(t, errors.Is(collisions[0], vending.ErrOutputCollision))
+	assert.Contains(t, a.Files, "README.md")
+	assert.Contains(t, b.Files, "README.md")
+}
+
+func TestResolveCollisions_SameContents_AreKept(t *testing.T) {
+	a := newOutput("a", 0, map[string]string{"LICENSE": "digest"})
+	b := newOutput("b", 0, map[string]string{"LICENSE": "digest"})
+
+	collisions := ResolveCollisions([]*Output{a, b})
+
+	assert.Empty(t, collisions)
+	assert.Contains(t, a.Files, "LICENSE")
+	assert.Contains(t, b.Files, "LICENSE")
+}
+
+func TestResolveCollisions_HighestPriorityWins(t *testing.T) {
+	a := newOutput("a", 0, map[string]string{"README.md": "digest-a", "a.proto": "digest-a"})
+	b := newOutput("b", 1, map[string]string{"README.md": "digest-b"})
+	c := newOutput("c", 0, map[string]string{"README.md": "digest-c"})
+
+	collisions := ResolveCollisions([]*Output{a, b, c})
+
+	assert.Empty(t, collisions)
+	assert.Equal(t, map[string]string{"a.proto": "digest-a"}, a.Files)
+	assert.Equal(t, map[string]string{"README.md": "digest-b"}, b.Files)
+	assert.Empty(t, c.Files)
+}
@@ -0,0 +1,158 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alevinval/vendor-go/internal/glob"
+	"github.com/alevinval/vendor-go/pkg/vending"
+)
+
+const (
+	gitAttributesFilename = ".gitattributes"
+	vendorIgnoreFilename  = ".vendorignore"
+	exportIgnoreAttribute = "export-ignore"
+)
+
+// vcsNames are the files and directories of version control systems, they
+// are never vendored, whatever the filters say.
+var vcsNames = []string{".git", ".hg", ".svn"}
+
+func isVCS(name string) bool {
+	return slices.Contains(vcsNames, name)
+}
+
+// ignoreRule is a pattern, relative to the repository root, and whether the
+// paths it matches are ignored, or explicitly not ignored.
+type ignoreRule struct {
+	pattern string
+	ignored bool
+}
+
+// ignoreFile collects the rules of every file with the same name, found in
+// the directories of the repository. The last rule that matches a path
+// decides, so the rules of nested files take precedence. Hidden files are not
+// vendored themselves.
+type ignoreFile struct {
+	filename string
+	hidden   bool
+	parse    func(data []byte) []ignoreRule
+	rules    []ignoreRule
+}
+
+// ignoreFiles are the ignore files that the filters enable.
+type ignoreFiles []*ignoreFile
+
+func newIgnoreFiles(filters *vending.Filters) ignoreFiles {
+	files := ignoreFiles{}
+	if filters.ExportIgnore {
+		files = append(files, &ignoreFile{filename: gitAttributesFilename, parse: parseGitAttributes})
+	}
+	if filters.VendorIgnore {
+		files = append(files, &ignoreFile{filename: vendorIgnoreFilename, hidden: true, parse: parseVendorIgnore})
+	}
+	return files
+}
+
+// load reads the ignore files of dir, relative to root, and adds their rules.
+func (files ignoreFiles) load(root, dir string) error {
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(root, dir, file.filename))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("cannot read %s: %w", file.filename, err)
+		}
+		for _, rule := range file.parse(data) {
+			rule.pattern = anchor(filepath.ToSlash(dir), rule.pattern)
+			file.rules = append(file.rules, rule)
+		}
+	}
+	return nil
+}
+
+// match returns whether any of the ignore files ignores the path.
+func (files ignoreFiles) match(name string) bool {
+	name = filepath.ToSlash(name)
+	for _, file := range files {
+		if file.hidden && path.Base(name) == file.filename {
+			return true
+		}
+
+		ignored := false
+		for _, rule := range file.rules {
+			if glob.MatchTree(rule.pattern, name) {
+				ignored = rule.ignored
+			}
+		}
+		if ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// anchor makes a pattern of an ignore file in dir relative to the repository
+// root. Like in .gitignore, a pattern without a slash matches at any depth,
+// and a pattern with a slash is relative to dir.
+func anchor(dir, pattern string) string {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if strings.HasPrefix(pattern, "/") {
+		pattern = pattern[1:]
+	} else if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	return path.Join(dir, pattern)
+}
+
+// parseGitAttributes returns a rule for every pattern that sets, or unsets,
+// the export-ignore attribute.
+func parseGitAttributes(data []byte) []ignoreRule {
+	rules := []ignoreRule{}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fields := strings.Fields(string(line))
+		// Quoted patterns are not supported.
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], `"`) {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			switch attr {
+			case exportIgnoreAttribute:
+				rules = append(rules, ignoreRule{pattern: fields[0], ignored: true})
+			case "-" + exportIgnoreAttribute, "!" + exportIgnoreAttribute:
+				rules = append(rules, ignoreRule{pattern: fields[0], ignored: false})
+			}
+		}
+	}
+	return rules
+}
+
+// parseVendorIgnore returns a rule for every pattern, patterns starting with
+// "!" are negated.
+func parseVendorIgnore(data []byte) []ignoreRule {
+	rules := []ignoreRule{}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		pattern := strings.TrimSpace(string(line))
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		rule := ignoreRule{pattern: pattern, ignored: true}
+		if strings.HasPrefix(pattern, "!") {
+			rule = ignoreRule{pattern: pattern[1:], ignored: false}
+		} else if strings.HasPrefix(pattern, `\`) {
+			rule.pattern = pattern[1:]
+		}
+		if rule.pattern != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
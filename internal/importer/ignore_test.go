@@ -0,0 +1,45 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnchor(t *testing.T) {
+	assert.Equal(t, "**/draft.md", anchor(".", "draft.md"))
+	assert.Equal(t, "docs/**/draft.md", anchor("docs", "draft.md"))
+	assert.Equal(t, "docs/tests", anchor("docs", "/tests/"))
+	assert.Equal(t, "docs/tests/*.txt", anchor("docs", "tests/*.txt"))
+}
+
+func TestParseGitAttributes(t *testing.T) {
+	rules := parseGitAttributes([]byte(`# comment
+/tests      export-ignore
+*.go        text eol=lf
+.github     export-ignore linguist-vendored
+tests/keep  -export-ignore
+"quoted"    export-ignore
+`))
+
+	assert.Equal(t, []ignoreRule{
+		{pattern: "/tests", ignored: true},
+		{pattern: ".github", ignored: true},
+		{pattern: "tests/keep", ignored: false},
+	}, rules)
+}
+
+func TestParseVendorIgnore(t *testing.T) {
+	rules := parseVendorIgnore([]byte(`# comment
+
+generated/
+!generated/keep.txt
+\#literal
+`))
+
+	assert.Equal(t, []ignoreRule{
+		{pattern: "generated/", ignored: true},
+		{pattern: "generated/keep.txt", ignored: false},
+		{pattern: "#literal", ignored: true},
+	}, rules)
+}
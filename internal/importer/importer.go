@@ -8,49 +8,79 @@ import (
 	"strings"
 
 	"github.com/alevinval/vendor-go/internal/git"
+	"github.com/alevinval/vendor-go/pkg/event"
 	"github.com/alevinval/vendor-go/pkg/log"
 	"github.com/alevinval/vendor-go/pkg/vending"
 )
 
 // Importer knows how to copy files from a source path to a destination path.
 type Importer struct {
-	repo *git.Repository
-	spec *vending.Spec
-	dep  *vending.Dependency
+	repo      *git.Repository
+	spec      *vending.Spec
+	dep       *vending.Dependency
+	vendorDir string
+	sink      event.Sink
 }
 
-// New allocates a new Importer instance.
+// New allocates a new Importer instance, that copies files into the vendor
+// directory of the spec.
 func New(repo *git.Repository, spec *vending.Spec, dep *vending.Dependency) *Importer {
 	return &Importer{
 		repo,
 		spec,
 		dep,
+		spec.VendorDir,
+		event.Discard,
 	}
 }
 
+// WithVendorDir configures the directory where files are copied, instead of
+// the vendor directory of the spec.
+func (imp *Importer) WithVendorDir(vendorDir string) *Importer {
+	imp.vendorDir = vendorDir
+	return imp
+}
+
+// WithSink configures the sink that receives a FileCopied event for every
+// imported file.
+func (imp *Importer) WithSink(sink event.Sink) *Importer {
+	imp.sink = sink
+	return imp
+}
+
 // Import executes the import operation by copying files from the source to the
-// destination.
-func (imp *Importer) Import() error {
+// destination. It returns the digest of every imported file, keyed by its path
+// relative to the vendor directory.
+func (imp *Importer) Import() (map[string]string, error) {
 	collector, err := imp.collect()
 	if err != nil {
-		return fmt.Errorf("cannot collect: %w", err)
+		return nil, fmt.Errorf("cannot collect: %w", err)
 	}
-	err = collector.copyAll()
+	files, err := collector.copyAll(func(path string) {
+		imp.sink.Emit(event.FileCopied{Dependency: imp.dep.ID(), Path: path})
+	})
 	if err != nil {
-		return fmt.Errorf("cannot copyAll: %w", err)
+		return nil, fmt.Errorf("cannot copyAll: %w", err)
 	}
-	return nil
+	return files, nil
 }
 
 func (imp *Importer) collect() (*targetCollector, error) {
+	if err := imp.dep.CheckPaths(); err != nil {
+		return nil, fmt.Errorf("invalid dependency %s: %w", imp.dep.ID(), err)
+	}
+
 	selector := newSelector(imp.spec, imp.dep)
+	ignores := newIgnoreFiles(imp.spec.FiltersFor(imp.dep))
 	targetCollector := &targetCollector{targets: []target{}}
 
 	err := imp.repo.WalkDir(
 		collectTargetsFunc(
 			imp.repo.Path(),
-			imp.spec.VendorDir,
+			imp.vendorDir,
+			imp.dep,
 			selector,
+			ignores,
 			targetCollector,
 		),
 	)
@@ -60,7 +90,9 @@ func (imp *Importer) collect() (*targetCollector, error) {
 
 func collectTargetsFunc(
 	srcRoot, dstRoot string,
+	dep *vending.Dependency,
 	selector *Selector,
+	ignores ignoreFiles,
 	collector *targetCollector,
 ) fs.WalkDirFunc {
 	return func(path string, entry os.DirEntry, err error) error {
@@ -70,7 +102,7 @@ func collectTargetsFunc(
 		}
 
 		if strings.EqualFold(srcRoot, path) {
-			return nil
+			return ignores.load(srcRoot, ".")
 		}
 
 		pathRel, err := filepath.Rel(srcRoot, path)
@@ -78,15 +110,33 @@ func collectTargetsFunc(
 			return fmt.Errorf("cannot get relative path: %w", err)
 		}
 
-		if entry.IsDir() && !selector.SelectDir(pathRel) {
+		// Repository internals are never vendored, nor what the ignore
+		// files of the repository exclude.
+		if isVCS(entry.Name()) || ignores.match(pathRel) {
 			log.S().Debugf("  [skip] %s", pathRel)
-			return fs.SkipDir
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			if !selector.SelectDir(pathRel) {
+				log.S().Debugf("  [skip] %s", pathRel)
+				return fs.SkipDir
+			}
+			return ignores.load(srcRoot, pathRel)
 		} else if selector.SelectPath(pathRel) {
+			dstRel, err := dep.TargetPath(pathRel)
+			if err != nil {
+				return fmt.Errorf("cannot map target path: %w", err)
+			}
 			collector.add(
 				target{
 					src:    path,
 					srcRel: pathRel,
-					dst:    filepath.Join(dstRoot, pathRel),
+					dst:    filepath.Join(dstRoot, dstRel),
+					dstRel: dstRel,
 				},
 			)
 		}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/alevinval/vendor-go/internal/git"
 	"github.com/alevinval/vendor-go/internal/lock"
+	"github.com/alevinval/vendor-go/pkg/event"
 	"github.com/alevinval/vendor-go/pkg/log"
 	"github.com/alevinval/vendor-go/pkg/vending"
 	"github.com/stretchr/testify/assert"
@@ -47,6 +48,189 @@ func TestImporter_Import_VendorsFiles(t *testing.T) {
 	assertNotExists(t, vendorPath("target/nested_ignored/ignored.txt"))
 }
 
+func TestImporter_Import_ReturnsFileDigests(t *testing.T) {
+	filters := vending.NewFilters().
+		AddExtension("txt").
+		AddTarget("target")
+
+	sut := setUp(t, filters, []string{"target/a.txt", "target/nested/b.txt"})
+	defer cleanUp(t)
+
+	sut.dep.Dest = "dest"
+	files, err := sut.Import()
+
+	emptyDigest := "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"dest/target/a.txt":        emptyDigest,
+		"dest/target/nested/b.txt": emptyDigest,
+	}, files)
+}
+
+func TestImporter_Import_EmitsFileCopied(t *testing.T) {
+	filters := vending.NewFilters().
+		AddExtension("txt").
+		AddTarget("target")
+
+	sut := setUp(t, filters, []string{"target/a.txt"})
+	defer cleanUp(t)
+
+	events := []event.Event{}
+	sut.WithSink(event.SinkFunc(func(e event.Event) {
+		events = append(events, e)
+	}))
+	_, err := sut.Import()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []event.Event{
+		event.FileCopied{Dependency: sut.dep.ID(), Path: "target/a.txt"},
+	}, events)
+}
+
+func TestImporter_Import_WithGlobs(t *testing.T) {
+	filters := vending.NewFilters().
+		AddExtension("proto").
+		AddTarget("api/*/v1").
+		AddIgnore("**/testdata/**")
+
+	filepaths := []string{
+		"api/ledger/v1/ledger.proto",
+		"api/ledger/v1/testdata/fixture.proto",
+		"api/ledger/v2/ledger.proto",
+		"apis/ledger/v1/ledger.proto",
+	}
+
+	sut := setUp(t, filters, filepaths)
+	defer cleanUp(t)
+
+	sut.Import()
+
+	assertExists(t, vendorPath("api/ledger/v1/ledger.proto"))
+
+	assertNotExists(t, vendorPath("api/ledger/v1/testdata/fixture.proto"))
+	assertNotExists(t, vendorPath("api/ledger/v2/ledger.proto"))
+	assertNotExists(t, vendorPath("apis/ledger/v1/ledger.proto"))
+}
+
+func TestImporter_Import_WithDestAndStrip(t *testing.T) {
+	filters := vending.NewFilters().
+		AddExtension("proto").
+		AddTarget("pkg/proto")
+
+	filepaths := []string{
+		"pkg/proto/ledger.proto",
+		"pkg/proto/v1/checkpoint.proto",
+	}
+
+	sut := setUp(t, filters, filepaths)
+	defer cleanUp(t)
+
+	sut.dep.Dest = "third_party/ledger"
+	sut.dep.Strip = "pkg/proto"
+	sut.Import()
+
+	assertExists(t, vendorPath("third_party/ledger/ledger.proto"))
+	assertExists(t, vendorPath("third_party/ledger/v1/checkpoint.proto"))
+
+	assertNotExists(t, vendorPath("pkg/proto/ledger.proto"))
+}
+
+func TestImporter_Import_WithTraversalDest_Fails(t *testing.T) {
+	sut := setUp(t, vending.NewFilters().AddExtension("txt"), []string{"root.txt"})
+	defer cleanUp(t)
+
+	sut.dep.Dest = "../outside"
+
+	_, err := sut.Import()
+	assert.Error(t, err)
+}
+
+func TestImporter_Import_NeverVendorsVCSMetadata(t *testing.T) {
+	filters := vending.NewFilters().
+		AddExtension("*")
+
+	filepaths := []string{
+		"root.txt",
+		".git/hooks/pre-commit.sample",
+		".git/objects/pack/pack-1.pack",
+		"submodule/.git",
+		"submodule/nested.txt",
+	}
+
+	sut := setUp(t, filters, filepaths)
+	defer cleanUp(t)
+
+	sut.Import()
+
+	assertExists(t, vendorPath("root.txt"))
+	assertExists(t, vendorPath("submodule/nested.txt"))
+
+	assertNotExists(t, vendorPath(".git"))
+	assertNotExists(t, vendorPath("submodule/.git"))
+}
+
+func TestImporter_Import_WithExportIgnore(t *testing.T) {
+	filters := vending.NewFilters().
+		AddExtension("txt")
+
+	filepaths := []string{
+		"root.txt",
+		"tests/root_test.txt",
+		"docs/kept.txt",
+		"docs/draft.txt",
+	}
+
+	sut := setUp(t, filters, filepaths)
+	defer cleanUp(t)
+
+	writeInput(t, ".gitattributes", "/tests export-ignore\n*.md linguist-documentation\n")
+	writeInput(t, "docs/.gitattributes", "draft.txt export-ignore\n")
+
+	sut.Import()
+	assertExists(t, vendorPath("tests/root_test.txt"))
+	assertExists(t, vendorPath("docs/draft.txt"))
+	cleanUpVendor(t)
+
+	sut.spec.Filters.ExportIgnore = true
+	sut.Import()
+
+	assertExists(t, vendorPath("root.txt"))
+	assertExists(t, vendorPath("docs/kept.txt"))
+
+	assertNotExists(t, vendorPath("tests/root_test.txt"))
+	assertNotExists(t, vendorPath("docs/draft.txt"))
+}
+
+func TestImporter_Import_WithVendorIgnore(t *testing.T) {
+	filters := vending.NewFilters().
+		AddExtension("txt")
+
+	filepaths := []string{
+		"root.txt",
+		"generated/a.txt",
+		"docs/a.txt",
+		"docs/keep.txt",
+		"docs/nested/b.txt",
+	}
+
+	sut := setUp(t, filters, filepaths)
+	defer cleanUp(t)
+
+	writeInput(t, ".vendorignore", "# generated code\ngenerated/\n")
+	writeInput(t, "docs/.vendorignore", "*.txt\n!keep.txt\n")
+
+	sut.dep.Filters.VendorIgnore = true
+	sut.Import()
+
+	assertExists(t, vendorPath("root.txt"))
+	assertExists(t, vendorPath("docs/keep.txt"))
+
+	assertNotExists(t, vendorPath("generated/a.txt"))
+	assertNotExists(t, vendorPath("docs/a.txt"))
+	assertNotExists(t, vendorPath("docs/nested/b.txt"))
+	assertNotExists(t, vendorPath(".vendorignore"))
+}
+
 func assertExists(t *testing.T, filepath string) {
 	_, err := os.Stat(filepath)
 	assert.NoError(t, err, fmt.Sprintf("%q should exist, but it does not", filepath))
@@ -81,6 +265,15 @@ func setUp(t *testing.T, filters *vending.Filters, filepaths []string) *Importer
 	return New(repo, spec, dep)
 }
 
+func writeInput(t *testing.T, filepath, data string) {
+	err := os.WriteFile(path.Join(INPUT_DIR, filepath), []byte(data), 0o644)
+	assert.NoError(t, err)
+}
+
+func cleanUpVendor(t *testing.T) {
+	os.RemoveAll(VENDOR_DIR)
+}
+
 func cleanUp(t *testing.T) {
 	os.RemoveAll(VENDOR_DIR)
 	os.RemoveAll(INPUT_DIR)
@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/alevinval/vendor-go/internal/glob"
 	"github.com/alevinval/vendor-go/pkg/vending"
 )
 
@@ -12,7 +13,7 @@ type Selector struct {
 }
 
 func newSelector(spec *vending.Spec, dep *vending.Dependency) *Selector {
-	filters := spec.Filters.Clone().ApplyFilters(dep.Filters)
+	filters := spec.FiltersFor(dep)
 
 	return &Selector{
 		filters,
@@ -27,21 +28,27 @@ func (sel *Selector) SelectPath(path string) bool {
 // SelectDir determines if a directory should be walked or not.
 func (sel *Selector) SelectDir(dir string) bool {
 	return !sel.isIgnored(dir) && (len(sel.filters.Targets) == 0 ||
-		inverseHasPrefix(sel.filters.Targets, dir))
+		matchWithin(sel.filters.Targets, dir))
 }
 
 func (sel *Selector) isTarget(path string) bool {
-	return hasPrefix(path, sel.filters.Targets) || len(sel.filters.Targets) == 0
+	return matchTree(path, sel.filters.Targets) || len(sel.filters.Targets) == 0
 }
 
 func (sel *Selector) isIgnored(path string) bool {
-	return hasPrefix(path, sel.filters.Ignores)
+	return matchTree(path, sel.filters.Ignores)
 }
 
 func (sel *Selector) hasExt(path string) bool {
+	for _, targetExt := range sel.filters.Extensions {
+		if targetExt == vending.AnyExtension {
+			return true
+		}
+	}
+
 	ext := filepath.Ext(path)
 	if ext == "" {
-		return false
+		return hasPerfectMatch(path, sel.filters.Targets)
 	}
 
 	// Ignore initial dot that filepath.Ext returns
@@ -59,44 +66,33 @@ func (sel *Selector) hasExt(path string) bool {
 	return hasPerfectMatch(path, sel.filters.Targets)
 }
 
-func hasPrefix(path string, prefixes []string) bool {
-	for _, prefix := range prefixes {
-		if strings.HasPrefix(path, prefix) {
+func matchTree(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if glob.MatchTree(pattern, path) {
 			return true
 		}
 	}
 	return false
 }
 
-func hasPerfectMatch(path string, prefixes []string) bool {
-	for _, prefix := range prefixes {
-		if path == prefix {
+// hasPerfectMatch only considers literal targets, glob targets still need the
+// file to have one of the supported extensions.
+func hasPerfectMatch(path string, targets []string) bool {
+	for _, target := range targets {
+		if !glob.HasMeta(target) && glob.Match(target, path) {
 			return true
 		}
 	}
 	return false
 }
 
-// inverseHasPrefix is used to determine when the WalkDirFunc should enter inside
+// matchWithin is used to determine when the WalkDirFunc should enter inside
 // a directory whenever a path has not been selected.
-func inverseHasPrefix(paths []string, prefix string) bool {
-	prefix = normDir(prefix)
-	for _, path := range paths {
-		path = normDir(path)
-		if len(prefix) > len(path) {
-			path, prefix = prefix, path
-		}
-		if strings.HasPrefix(path, prefix) {
+func matchWithin(patterns []string, dir string) bool {
+	for _, pattern := range patterns {
+		if glob.MatchWithin(pattern, dir) {
 			return true
 		}
 	}
 	return false
 }
-
-func normDir(path string) string {
-	pathDir := filepath.Dir(path)
-	if pathDir == "." {
-		pathDir = path
-	}
-	return pathDir
-}
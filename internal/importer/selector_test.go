@@ -40,7 +40,7 @@ func TestSelectorSelect_WithTargets(t *testing.T) {
 	assertSelection(t, sut, "ignored/a/ignored.proto", false, false)
 	assertSelection(t, sut, "target/a/readme.md", false, true)
 	assertSelection(t, sut, "target/a/no-extension", false, true)
-	assertSelection(t, sut, "readme.md", true, false)
+	assertSelection(t, sut, "readme.md", true, true)
 
 	// Dirs
 	assertSelection(t, sut, "nontarget/a/b", false, false)
@@ -69,6 +69,51 @@ func TestSelectorSelect_WithoutTargets(t *testing.T) {
 	assertSelection(t, sut, "ignored/a/b", false, false)
 }
 
+func TestSelectorSelect_MatchesPathSegments(t *testing.T) {
+	sut := Selector{
+		filters: vending.NewFilters().
+			AddExtension("go").
+			AddIgnore("doc"),
+	}
+
+	assertSelection(t, sut, "doc/main.go", false, false)
+	assertSelection(t, sut, "docs/main.go", true, true)
+	assertSelection(t, sut, "doc.go", true, true)
+}
+
+func TestSelectorSelect_WithGlobs(t *testing.T) {
+	sut := Selector{
+		filters: vending.NewFilters().
+			AddExtension("proto").
+			AddTarget("api/*/v1/*.proto").
+			AddIgnore("**/testdata/**"),
+	}
+
+	// Filepaths
+	assertSelection(t, sut, "api/ledger/v1/ledger.proto", true, true)
+	assertSelection(t, sut, "api/ledger/v2/ledger.proto", false, false)
+	assertSelection(t, sut, "api/testdata/v1/ledger.proto", false, false)
+
+	// Dirs
+	assertSelection(t, sut, "api", false, true)
+	assertSelection(t, sut, "api/ledger", false, true)
+	assertSelection(t, sut, "api/ledger/v2", false, false)
+	assertSelection(t, sut, "api/testdata", false, false)
+	assertSelection(t, sut, "docs", false, false)
+}
+
+func TestSelectorSelect_WithAnyExtension(t *testing.T) {
+	sut := Selector{
+		filters: vending.NewFilters().
+			AddExtension(vending.AnyExtension).
+			AddTarget("bin"),
+	}
+
+	assertSelection(t, sut, "bin/run", true, true)
+	assertSelection(t, sut, "bin/run.sh", true, true)
+	assertSelection(t, sut, "lib/run", false, false)
+}
+
 func assertSelection(
 	t *testing.T, sut Selector, path string,
 	expectedIsSelected, expectedShouldEnterDir bool,
@@ -1,111 +1,166 @@
 package installer
 
 import (
+	"context"
 	"fmt"
+	"slices"
 
 	"github.com/alevinval/vendor-go/internal/git"
 	"github.com/alevinval/vendor-go/internal/importer"
+	"github.com/alevinval/vendor-go/pkg/event"
 	"github.com/alevinval/vendor-go/pkg/log"
 	"github.com/alevinval/vendor-go/pkg/vending"
 	"github.com/fatih/color"
 )
 
 type dependencyInstaller struct {
+	spec    *vending.Spec
 	dep     *vending.Dependency
 	depLock *vending.DependencyLock
 	repo    *git.Repository
 	imp     *importer.Importer
+	fetched *FetchSet
 }
 
-func newDependencyInstaller(spec *vending.Spec, dep *vending.Dependency, depLock *vending.DependencyLock, repo *git.Repository) *dependencyInstaller {
-	imp := importer.New(repo, spec, dep)
+func newDependencyInstaller(spec *vending.Spec, dep *vending.Dependency, depLock *vending.DependencyLock, repo *git.Repository, vendorDir string, sink event.Sink, fetched *FetchSet) *dependencyInstaller {
+	imp := importer.New(repo, spec, dep).WithVendorDir(vendorDir).WithSink(sink)
 
 	return &dependencyInstaller{
+		spec:    spec,
 		dep:     dep,
 		depLock: depLock,
 		repo:    repo,
 		imp:     imp,
+		fetched: fetched,
 	}
 }
 
-func (d *dependencyInstaller) Install() (*vending.DependencyLock, error) {
-	lock, err := d.repo.Lock()
+func (d *dependencyInstaller) Install(ctx context.Context) (*vending.DependencyLock, error) {
+	lock, err := d.repo.Lock(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("cannot lock repository: %w", err)
 	}
 	defer lock.Release()
 
-	err = d.repo.OpenOrClone()
+	err = d.repo.OpenOrClone(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open repository: %w", err)
 	}
 
-	var refname, refnameLog string
-	if d.depLock == nil {
-		refname = d.dep.Branch
-		refnameLog = refname
-	} else {
-		refname = d.depLock.Commit
+	refnameLog := d.dep.Revision()
+	if d.depLock != nil {
 		refnameLog = fmt.Sprintf("%.8s", d.depLock.Commit)
 	}
 
-	doReset := func(fetch bool) error {
+	doReset := func(fetch bool) (string, error) {
 		if fetch {
-			err = d.repo.Fetch()
+			err = d.fetched.fetch(ctx, d.repo)
 			if err != nil {
-				return fmt.Errorf("cannot fetch repository: %w", err)
+				return "", fmt.Errorf("cannot fetch repository: %w", err)
 			}
 		} else {
 			log.S().Infof("installing %s@%s",
-				color.CyanString(d.dep.URL),
+				color.CyanString(d.dep.ID()),
 				color.YellowString(
 					refnameLog,
 				),
 			)
 		}
-		return d.repo.Reset(refname)
+		if d.depLock != nil {
+			return d.depLock.Tag, d.repo.Reset(ctx, d.depLock.Commit)
+		}
+		return d.resolveAndReset(ctx)
 	}
 
-	if err = doReset(false); err != nil {
-		if err = doReset(true); err != nil {
+	tag, err := doReset(false)
+	if err != nil {
+		if tag, err = doReset(true); err != nil {
 			return nil, fmt.Errorf("cannot reset repository: %w", err)
 		}
 	}
-	return d.importFiles()
+
+	depLock, err := d.importFiles(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	// The commit is the locked one, so is the revision it was resolved from,
+	// whatever the spec says now.
+	if d.depLock != nil {
+		depLock.Branch, depLock.Ref = d.depLock.Branch, d.depLock.Ref
+	}
+	return depLock, nil
 }
 
-func (d *dependencyInstaller) Update() (*vending.DependencyLock, error) {
-	lock, err := d.repo.Lock()
+func (d *dependencyInstaller) Update(ctx context.Context) (*vending.DependencyLock, error) {
+	lock, err := d.repo.Lock(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("cannot lock repository: %w", err)
 	}
 	defer lock.Release()
 
-	err = d.repo.OpenOrClone()
+	err = d.repo.OpenOrClone(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("cannot open repository: %s", err)
+		return nil, fmt.Errorf("cannot open repository: %w", err)
 	}
 
 	log.S().Infof("updating %s@%s",
-		color.CyanString("%s", d.dep.URL),
-		color.YellowString(d.dep.Branch),
+		color.CyanString("%s", d.dep.ID()),
+		color.YellowString(d.dep.Revision()),
 	)
 
-	err = d.repo.Fetch()
+	err = d.fetched.fetch(ctx, d.repo)
 	if err != nil {
 		return nil, fmt.Errorf("cannot fetch repository: %w", err)
 	}
 
-	err = d.repo.Reset(d.dep.Branch)
+	tag, err := d.resolveAndReset(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("cannot reset repository: %w", err)
 	}
 
-	return d.importFiles()
+	return d.importFiles(ctx, tag)
 }
 
-func (d *dependencyInstaller) importFiles() (*vending.DependencyLock, error) {
-	err := d.imp.Import()
+// resolveAndReset checks out the revision of the dependency. Semver constraints
+// are resolved to the latest tag that satisfies them. It returns the name of
+// the tag that was checked out, if any.
+func (d *dependencyInstaller) resolveAndReset(ctx context.Context) (string, error) {
+	refname := d.dep.Revision()
+
+	tags, err := d.repo.Tags()
+	if err != nil {
+		return "", fmt.Errorf("cannot list tags: %w", err)
+	}
+
+	tag := ""
+	if vending.IsConstraint(refname) {
+		constraint, err := vending.ParseConstraint(refname)
+		if err != nil {
+			return "", fmt.Errorf("cannot parse ref: %w", err)
+		}
+		latest, ok := constraint.Latest(tags)
+		if !ok {
+			return "", fmt.Errorf("no tag satisfies %q", refname)
+		}
+		log.S().Infof("resolved %s@%s to %s",
+			color.CyanString(d.dep.ID()),
+			color.YellowString(refname),
+			color.YellowString(latest),
+		)
+		tag, refname = latest, latest
+	} else if slices.Contains(tags, refname) {
+		tag = refname
+	}
+
+	return tag, d.repo.Reset(ctx, refname)
+}
+
+func (d *dependencyInstaller) importFiles(ctx context.Context, tag string) (*vending.DependencyLock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	files, err := d.imp.Import()
 	if err != nil {
 		return nil, fmt.Errorf("cannot import: %w", err)
 	}
@@ -115,5 +170,12 @@ func (d *dependencyInstaller) importFiles() (*vending.DependencyLock, error) {
 		return nil, fmt.Errorf("cannot get current commit: %w", err)
 	}
 
-	return vending.NewDependencyLock(d.dep.URL, commit), nil
+	depLock := vending.NewDependencyLock(d.dep.URL, commit)
+	depLock.Name = d.dep.Name
+	depLock.Tag = tag
+	depLock.Branch = d.dep.Branch
+	depLock.Ref = d.dep.Ref
+	depLock.FiltersDigest = d.spec.FiltersDigest(d.dep)
+	depLock.SetFiles(files)
+	return depLock, nil
 }
@@ -0,0 +1,45 @@
+package installer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alevinval/vendor-go/internal/git"
+)
+
+// FetchSet remembers which repositories have been fetched. Installers that
+// share it fetch every repository once, even when several specs depend on it.
+type FetchSet struct {
+	mu      sync.Mutex
+	fetched map[string]bool
+}
+
+// NewFetchSet allocates an empty FetchSet.
+func NewFetchSet() *FetchSet {
+	return &FetchSet{fetched: map[string]bool{}}
+}
+
+// fetch fetches the repository, unless it was already fetched. A nil FetchSet
+// always fetches. The repository must be locked, so it is never fetched twice
+// at the same time.
+func (s *FetchSet) fetch(ctx context.Context, repo *git.Repository) error {
+	if s == nil {
+		return repo.Fetch(ctx)
+	}
+
+	s.mu.Lock()
+	done := s.fetched[repo.Path()]
+	s.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	if err := repo.Fetch(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.fetched[repo.Path()] = true
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,57 @@
+package installer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alevinval/vendor-go/internal/cache"
+	"github.com/alevinval/vendor-go/internal/git"
+	"github.com/alevinval/vendor-go/pkg/event"
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFetchedRepository returns a clone of the upstream, and counts how many
+// times it is fetched.
+func newFetchedRepository(t *testing.T, c *cache.Cache, url string) (*git.Repository, *atomic.Int32) {
+	repo, err := c.GetRepository(vending.NewDependency(url, "master"))
+	require.NoError(t, err)
+	require.NoError(t, repo.OpenOrClone(context.Background()))
+
+	fetches := &atomic.Int32{}
+	repo.WithSink(event.SinkFunc(func(e event.Event) {
+		if _, ok := e.(event.FetchStarted); ok {
+			fetches.Add(1)
+		}
+	}))
+	return repo, fetches
+}
+
+func TestFetchSet_FetchesOnce(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	c := cache.New(t.TempDir())
+	repo, fetches := newFetchedRepository(t, c, up.dir)
+	// Another dependency on the same repository shares its clone.
+	other, otherFetches := newFetchedRepository(t, c, up.dir+"/")
+
+	sut := NewFetchSet()
+	assert.NoError(t, sut.fetch(context.Background(), repo))
+	assert.NoError(t, sut.fetch(context.Background(), repo))
+	assert.NoError(t, sut.fetch(context.Background(), other))
+
+	assert.Equal(t, int32(1), fetches.Load())
+	assert.Equal(t, int32(0), otherFetches.Load())
+}
+
+func TestFetchSet_WhenNil_AlwaysFetches(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	repo, fetches := newFetchedRepository(t, cache.New(t.TempDir()), up.dir)
+
+	var sut *FetchSet
+	assert.NoError(t, sut.fetch(context.Background(), repo))
+	assert.NoError(t, sut.fetch(context.Background(), repo))
+
+	assert.Equal(t, int32(2), fetches.Load())
+}
@@ -1,137 +1,538 @@
 package installer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/alevinval/vendor-go/internal/cache"
+	"github.com/alevinval/vendor-go/internal/importer"
+	"github.com/alevinval/vendor-go/internal/txn"
+	"github.com/alevinval/vendor-go/pkg/event"
 	"github.com/alevinval/vendor-go/pkg/log"
 	"github.com/alevinval/vendor-go/pkg/vending"
 	"github.com/fatih/color"
 )
 
+// Installer vendors the dependencies of a spec. Files are staged into a
+// temporary directory next to the vendor directory, one per dependency, and
+// only moved into the vendor directory on Commit, once every dependency has
+// been vendored.
+// Dependencies whose files did not change are not touched, and only the files
+// that changed are moved.
 type Installer struct {
-	spec     *vending.Spec
-	specLock *vending.SpecLock
-	cache    *cache.Cache
+	spec        *vending.Spec
+	specLock    *vending.SpecLock
+	cache       *cache.Cache
+	vendorDir   string
+	stagingDir  string
+	jobs        int
+	jobsPerHost int
+	keepGoing   bool
+	sink        event.Sink
+	fetched     *FetchSet
+	selected    map[string]bool
+	merged      []*Result
+	pruned      []*vending.DependencyLock
 }
 
 func New(cache *cache.Cache, spec *vending.Spec, specLock *vending.SpecLock) *Installer {
 	return &Installer{
-		spec,
-		specLock,
-		cache,
+		spec:      spec,
+		specLock:  specLock,
+		cache:     cache,
+		vendorDir: spec.VendorDir,
+		jobs:      vending.DefaultJobs,
+		sink:      event.Discard,
 	}
 }
 
-func (in *Installer) Install() error {
-	return in.runInParallel(installFunc)
+// WithVendorDir configures the vendor directory, instead of the one of the
+// spec. It is used when the spec is not in the working directory.
+func (in *Installer) WithVendorDir(vendorDir string) *Installer {
+	in.vendorDir = vendorDir
+	return in
 }
 
-func (in *Installer) Update() error {
-	return in.runInParallel(updateFunc)
+// WithSink configures the sink that receives the events of vendoring every
+// dependency.
+func (in *Installer) WithSink(sink event.Sink) *Installer {
+	in.sink = sink
+	return in
 }
 
-func (in *Installer) runInParallel(action actionFunc) error {
-	err := resetVendorDir(in.spec.VendorDir)
-	if err != nil {
-		return err
+// WithFetchSet shares the repositories fetched with other installers, so each
+// repository is fetched once.
+func (in *Installer) WithFetchSet(fetched *FetchSet) *Installer {
+	in.fetched = fetched
+	return in
+}
+
+// WithSelection restricts the installer to the given dependencies. The other
+// dependencies keep their lock, and their files are not touched.
+func (in *Installer) WithSelection(deps []*vending.Dependency) *Installer {
+	in.selected = map[string]bool{}
+	for _, dep := range deps {
+		in.selected[strings.ToLower(dep.ID())] = true
 	}
+	return in
+}
 
-	n := len(in.spec.Deps)
-	out := make(chan *vending.DependencyLock, n)
-	errors := make(chan error, 1)
-	wg := &sync.WaitGroup{}
-	wg.Add(n)
+// WithJobs limits how many dependencies are vendored at the same time, and how
+// many of them are fetched from the same host. Zero jobsPerHost means there is
+// no limit per host.
+func (in *Installer) WithJobs(jobs, jobsPerHost int) *Installer {
+	in.jobs = jobs
+	in.jobsPerHost = jobsPerHost
+	return in
+}
+
+// WithKeepGoing makes the installer stage every dependency that succeeded,
+// even when others fail. Failed dependencies keep the files, and the lock,
+// they had before.
+func (in *Installer) WithKeepGoing(keepGoing bool) *Installer {
+	in.keepGoing = keepGoing
+	return in
+}
+
+// Install vendors the dependencies at their locked commit. Dependencies that
+// are locked with the same filters, and whose files in the vendor directory
+// match the lock, are skipped. The report holds the outcome of every
+// dependency, the error is set when any of them failed.
+func (in *Installer) Install(ctx context.Context) (*Report, error) {
+	return in.runInParallel(ctx, installFunc, in.isUnchanged)
+}
 
-	for _, dep := range in.spec.Deps {
-		go in.runInBackground(wg, action, dep, out, errors)
+// Update vendors the dependencies at the latest commit of their revision. The
+// report holds the outcome of every dependency, the error is set when any of
+// them failed.
+func (in *Installer) Update(ctx context.Context) (*Report, error) {
+	return in.runInParallel(ctx, updateFunc, nil)
+}
+
+// Commit applies the changes to the vendor directory as part of the
+// transaction: the staged files that differ from the vendored ones are moved
+// into it, and the files that are no longer vendored by any dependency are
+// removed. Files that no lock records are left untouched, unless the files of
+// a previous lock were never recorded: then every one of them is removed, as
+// the vendor directory cannot tell which ones are stale.
+func (in *Installer) Commit(tx *txn.Transaction) error {
+	if in.stagingDir == "" {
+		return fmt.Errorf("nothing has been staged")
 	}
 
-	completed := make(chan struct{}, 1)
-	go func() {
-		wg.Wait()
-		close(completed)
-	}()
+	merged := map[string]bool{}
+	wanted := map[string]bool{}
+	for _, result := range in.merged {
+		merged[strings.ToLower(result.Dependency.ID())] = true
+		for path := range result.Lock.Files {
+			wanted[path] = true
+		}
+	}
+	for _, lock := range in.specLock.Deps {
+		if !merged[strings.ToLower(lock.ID())] {
+			for path := range lock.Files {
+				wanted[path] = true
+			}
+		}
+	}
 
-	var isCompleted bool
-	for !isCompleted {
-		select {
-		case err := <-errors:
+	previous := []map[string]string{}
+	for _, result := range in.merged {
+		if result.previous != nil {
+			previous = append(previous, result.previous.Files)
+		}
+	}
+	for _, lock := range in.pruned {
+		previous = append(previous, lock.Files)
+	}
+	if in.hasUnrecordedFiles() {
+		// Listed before anything is moved aside, into the vendor directory.
+		unrecorded, err := in.unrecordedFiles(wanted)
+		if err != nil {
 			return err
-		case <-completed:
-			isCompleted = true
+		}
+		previous = append(previous, unrecorded)
+	}
+
+	removed := []string{}
+	for _, files := range previous {
+		for path := range files {
+			if wanted[path] || !filepath.IsLocal(filepath.FromSlash(path)) {
+				continue
+			}
+			// Files shared by several locks are removed once.
+			wanted[path] = true
+			if err := tx.RemoveFile(filepath.Join(in.vendorDir, filepath.FromSlash(path))); err != nil {
+				return fmt.Errorf("cannot remove %s: %w", path, err)
+			}
+			log.S().Debugf("  [removed] %s", path)
+			removed = append(removed, path)
 		}
 	}
 
-	for n > 0 {
-		dependencyLock := <-out
+	for _, result := range in.merged {
+		// Dependencies that were not imported only lost files to collisions,
+		// the rest of their files are kept as they are.
+		if result.stagingDir == "" {
+			continue
+		}
+		for path, digest := range result.Lock.Files {
+			dst := filepath.Join(in.vendorDir, filepath.FromSlash(path))
+			// Also skips the files that several dependencies vendor with the
+			// same contents, once the first of them is moved.
+			if actual, err := vending.HashFile(dst); err == nil && actual == digest {
+				continue
+			}
+			if err := tx.ReplaceFile(filepath.Join(result.stagingDir, filepath.FromSlash(path)), dst); err != nil {
+				return fmt.Errorf("cannot replace %s: %w", path, err)
+			}
+			log.S().Debugf("  [replaced] %s", path)
+			result.Replaced = append(result.Replaced, path)
+		}
+		sort.Strings(result.Replaced)
+	}
 
-		log.S().Infof("locking %s\n  🔒 %s",
-			color.CyanString(dependencyLock.URL),
-			color.YellowString(dependencyLock.Commit),
-		)
-		in.specLock.AddDependencyLock(dependencyLock)
+	vendorDir := in.vendorDir
+	tx.OnCommit(func() {
+		txn.RemoveEmptyDirs(vendorDir, removed)
+	})
+	return in.Discard()
+}
+
+// hasUnrecordedFiles returns whether a previous lock, written before the files
+// of the locks were recorded, is replaced. The files of every lock have to be
+// recorded now, so none of them is taken as unrecorded.
+func (in *Installer) hasUnrecordedFiles() bool {
+	unrecorded := false
+	for _, result := range in.merged {
+		if result.previous != nil && result.previous.Digest == "" {
+			unrecorded = true
+		}
+	}
+	for _, lock := range in.pruned {
+		if lock.Digest == "" {
+			unrecorded = true
+		}
+	}
+	if !unrecorded {
+		return false
+	}
+
+	for _, lock := range in.specLock.Deps {
+		if lock.Digest == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// unrecordedFiles returns the files of the vendor directory that are not
+// wanted, as a set of paths.
+func (in *Installer) unrecordedFiles(wanted map[string]bool) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(in.vendorDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == in.vendorDir {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
 
-		n--
+		rel, err := filepath.Rel(in.vendorDir, path)
+		if err != nil {
+			return fmt.Errorf("cannot get relative path: %w", err)
+		}
+		if rel = filepath.ToSlash(rel); !wanted[rel] {
+			files[rel] = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot walk vendor dir: %w", err)
 	}
+	return files, nil
+}
 
-	in.specLock.Prune(in.spec)
+// Discard removes the staged files, if any, leaving the vendor directory
+// untouched.
+func (in *Installer) Discard() error {
+	if in.stagingDir == "" {
+		return nil
+	}
+	err := os.RemoveAll(in.stagingDir)
+	if err != nil {
+		return fmt.Errorf("cannot remove staging dir: %w", err)
+	}
+	in.stagingDir = ""
 	return nil
 }
 
-func (in *Installer) runInBackground(
-	wg *sync.WaitGroup,
-	action actionFunc,
-	dep *vending.Dependency,
-	out chan *vending.DependencyLock,
-	errors chan error,
-) {
-	defer wg.Done()
+func (in *Installer) runInParallel(ctx context.Context, action actionFunc, skip skipFunc) (*Report, error) {
+	stagingDir, err := txn.MkdirTemp(in.vendorDir, "staging")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create staging dir: %w", err)
+	}
+	in.stagingDir = stagingDir
+
+	report := &Report{Results: make([]*Result, len(in.spec.Deps))}
+	limiter := newLimiter(in.jobs, in.jobsPerHost)
+	wg := &sync.WaitGroup{}
+	wg.Add(len(in.spec.Deps))
+
+	for i, dep := range in.spec.Deps {
+		if !in.isSelected(dep) {
+			lock, _ := in.specLock.FindByID(dep.ID())
+			report.Results[i] = &Result{Dependency: dep, Status: StatusSkipped, Lock: lock, unchanged: true}
+			wg.Done()
+			continue
+		}
+		go func() {
+			defer wg.Done()
+			release, err := limiter.acquire(ctx, dep.Host())
+			if err != nil {
+				lock, _ := in.specLock.FindByID(dep.ID())
+				report.Results[i] = &Result{Dependency: dep, Status: StatusFailed, Lock: lock, Err: err}
+				return
+			}
+			defer release()
+			report.Results[i] = in.run(ctx, action, skip, dep)
+		}()
+	}
+
+	// Wait for every dependency, even after a failure, so nothing is still
+	// writing into the staging dir when it gets discarded.
+	wg.Wait()
+
+	// Nothing is kept when interrupted, not even with keepGoing.
+	if err := ctx.Err(); err != nil {
+		in.emitResults(report)
+		in.Discard()
+		return report, fmt.Errorf("interrupted: %w", err)
+	}
+
+	// Collisions are resolved before any event is emitted, so dependencies
+	// that lose them are only reported as failed.
+	in.resolveCollisions(report)
+	in.emitResults(report)
+
+	if err := report.Err(); err != nil && !in.keepGoing {
+		in.Discard()
+		return report, err
+	}
+
+	// Results are collected in spec order, whatever order they finished in,
+	// so the output and the lockfile are deterministic.
+	for _, result := range report.Results {
+		// Failed dependencies keep the files, and the lock, they had.
+		if result.Status == StatusFailed || result.unchanged {
+			continue
+		}
+
+		dependencyLock := result.Lock
+		if dependencyLock.Tag != "" {
+			log.S().Infof("locking %s\n  🔒 %s (%s)",
+				color.CyanString(dependencyLock.ID()),
+				color.YellowString(dependencyLock.Commit),
+				color.GreenString(dependencyLock.Tag),
+			)
+		} else {
+			log.S().Infof("locking %s\n  🔒 %s",
+				color.CyanString(dependencyLock.ID()),
+				color.YellowString(dependencyLock.Commit),
+			)
+		}
+		in.specLock.AddDependencyLock(dependencyLock)
+		in.merged = append(in.merged, result)
+	}
+
+	in.pruned = in.specLock.Prune(in.spec)
+	return report, report.Err()
+}
+
+func (in *Installer) isSelected(dep *vending.Dependency) bool {
+	return in.selected == nil || in.selected[strings.ToLower(dep.ID())]
+}
+
+func (in *Installer) run(ctx context.Context, action actionFunc, skip skipFunc, dep *vending.Dependency) *Result {
+	lock, _ := in.specLock.FindByID(dep.ID())
+	result := &Result{Dependency: dep, Lock: lock}
+	if lock != nil {
+		// A copy, since locking the dependency overwrites its lock in place.
+		previous := *lock
+		result.previous = &previous
+	}
+
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	if skip != nil && skip(dep, lock) {
+		log.S().Infof("%s %s@%s, it is up to date",
+			color.YellowString("skipping"),
+			color.CyanString(dep.ID()),
+			color.YellowString("%.8s", lock.Commit),
+		)
+		result.Status, result.unchanged = StatusSkipped, true
+		return result
+	}
+
+	in.sink.Emit(event.DependencyStarted{Dependency: dep.ID(), Revision: dep.Revision()})
 
 	repo, err := in.cache.GetRepository(dep)
 	if err != nil {
-		errors <- fmt.Errorf("cannot complete action: %w", err)
-		return
+		result.Status, result.Err = StatusFailed, fmt.Errorf("cannot complete action: %w", err)
+		return result
+	}
+
+	result.stagingDir, err = os.MkdirTemp(in.stagingDir, "dep")
+	if err != nil {
+		result.Status, result.Err = StatusFailed, fmt.Errorf("cannot create staging dir: %w", err)
+		return result
 	}
 
-	lock, _ := in.specLock.FindByURL(dep.URL)
-	dependencyInstaller := newDependencyInstaller(in.spec, dep, lock, repo)
+	repo.WithSink(in.sink)
+	dependencyInstaller := newDependencyInstaller(in.spec, dep, lock, repo, result.stagingDir, in.sink, in.fetched)
 
-	dependencyLock, err := action(dependencyInstaller)
+	dependencyLock, status, err := action(ctx, dependencyInstaller)
 	if err != nil {
-		errors <- fmt.Errorf("cannot complete action: %w", err)
+		result.Status, result.Err = StatusFailed, fmt.Errorf("cannot complete action: %w", err)
+		return result
+	}
+
+	result.Status, result.Lock = status, dependencyLock
+	return result
+}
+
+// emitResults emits, in spec order, whether every selected dependency was
+// locked or failed.
+func (in *Installer) emitResults(report *Report) {
+	for _, result := range report.Results {
+		if in.isSelected(result.Dependency) {
+			in.emitResult(result)
+		}
+	}
+}
+
+func (in *Installer) emitResult(result *Result) {
+	if result.Status == StatusFailed {
+		in.sink.Emit(event.DependencyFailed{Dependency: result.Dependency.ID(), Err: result.Err})
 		return
 	}
+	in.sink.Emit(event.DependencyLocked{
+		Dependency: result.Dependency.ID(),
+		Commit:     result.Lock.Commit,
+		Tag:        result.Lock.Tag,
+	})
+}
+
+// resolveCollisions resolves, by priority, the paths that several
+// dependencies vendor with different contents. Dependencies that vendored a
+// path that cannot be resolved fail, and keep the lock they had. The others
+// take part with the files of their lock, since those are kept in the vendor
+// directory.
+//
+// The paths that a dependency loses are recorded in its lock as shadowed, so
+// it is imported again, and gets them back once nothing shadows them.
+func (in *Installer) resolveCollisions(report *Report) {
+	outputs := []*importer.Output{}
+	results := map[*vending.Dependency]*Result{}
+	for _, result := range report.Results {
+		if result.Lock == nil {
+			continue
+		}
+		// A copy, since the files lost to other dependencies are deleted.
+		outputs = append(outputs, &importer.Output{Dependency: result.Dependency, Files: maps.Clone(result.Lock.Files)})
+		results[result.Dependency] = result
+	}
+
+	errs := map[*Result][]error{}
+	for _, collision := range importer.ResolveCollisions(outputs) {
+		for _, dep := range collision.Dependencies {
+			if result := results[dep]; result.Status != StatusFailed && !result.unchanged {
+				errs[result] = append(errs[result], collision)
+			}
+		}
+	}
 
-	out <- dependencyLock
-	return
+	for _, output := range outputs {
+		result := results[output.Dependency]
+		if len(errs[result]) > 0 {
+			result.Status, result.Err, result.Lock = StatusFailed, errors.Join(errs[result]...), result.previous
+			continue
+		}
+		if len(output.Files) == len(result.Lock.Files) {
+			continue
+		}
+
+		// Files lost to dependencies with higher priority were removed. The
+		// lock of an unchanged dependency is the one in the SpecLock, it is
+		// copied, and merged like the others.
+		lock := *result.Lock
+		for path := range lock.Files {
+			if _, ok := output.Files[path]; !ok {
+				lock.Shadowed = append(lock.Shadowed, path)
+			}
+		}
+		sort.Strings(lock.Shadowed)
+		lock.SetFiles(output.Files)
+		result.Lock, result.unchanged = &lock, false
+	}
 }
 
-func resetVendorDir(vendorDir string) error {
-	err := os.RemoveAll(vendorDir)
-	if err != nil {
-		return fmt.Errorf("cannot remove vendor dir: %w", err)
+// isUnchanged returns whether installing the dependency would vendor the
+// files it already has: it is locked from the url and with the filters of the
+// spec, and the files in the vendor directory match the digests of the lock.
+// Locks without digests, written before they were recorded, never are, and
+// neither are locks with shadowed paths, which could be vendored again.
+func (in *Installer) isUnchanged(dep *vending.Dependency, lock *vending.DependencyLock) bool {
+	if lock == nil || lock.Digest == "" || lock.Digest != vending.DigestFiles(lock.Files) || len(lock.Shadowed) > 0 {
+		return false
 	}
-	err = os.MkdirAll(vendorDir, os.ModePerm)
-	if err != nil {
-		return fmt.Errorf("cannot create vendor dir: %w", err)
+	if !strings.EqualFold(lock.URL, dep.URL) || lock.FiltersDigest != in.spec.FiltersDigest(dep) {
+		return false
 	}
-	return nil
+	for path, digest := range lock.Files {
+		actual, err := vending.HashFile(filepath.Join(in.vendorDir, filepath.FromSlash(path)))
+		if err != nil || actual != digest {
+			return false
+		}
+	}
+	return true
 }
 
-type actionFunc = func(*dependencyInstaller) (*vending.DependencyLock, error)
+type actionFunc = func(context.Context, *dependencyInstaller) (*vending.DependencyLock, Status, error)
+
+// skipFunc decides whether a dependency can be skipped, given its lock.
+type skipFunc = func(*vending.Dependency, *vending.DependencyLock) bool
 
-func installFunc(installer *dependencyInstaller) (*vending.DependencyLock, error) {
-	return installer.Install()
+func installFunc(ctx context.Context, installer *dependencyInstaller) (*vending.DependencyLock, Status, error) {
+	lock, err := installer.Install(ctx)
+	return lock, StatusSucceeded, err
 }
 
-func updateFunc(installer *dependencyInstaller) (*vending.DependencyLock, error) {
+func updateFunc(ctx context.Context, installer *dependencyInstaller) (*vending.DependencyLock, Status, error) {
 	if installer.dep.Pinned {
-		log.S().Infof("%s update for pinned dependency %s", color.RedString("skipping"), color.YellowString(installer.dep.URL))
-		return installer.Install()
-	} else {
-		return installer.Update()
+		if pin := installer.dep.Pin; pin != nil && pin.Reason != "" {
+			log.S().Infof("%s update for pinned dependency %s (%s)", color.RedString("skipping"), color.YellowString(installer.dep.ID()), pin.Reason)
+		} else {
+			log.S().Infof("%s update for pinned dependency %s", color.RedString("skipping"), color.YellowString(installer.dep.ID()))
+		}
+		lock, err := installer.Install(ctx)
+		return lock, StatusSkipped, err
 	}
+	lock, err := installer.Update(ctx)
+	return lock, StatusSucceeded, err
 }
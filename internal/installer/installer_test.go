@@ -0,0 +1,402 @@
+package installer
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alevinval/vendor-go/internal/cache"
+	"github.com/alevinval/vendor-go/internal/txn"
+	"github.com/alevinval/vendor-go/pkg/event"
+	"github.com/alevinval/vendor-go/pkg/vending"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upstream is a git repository that dependencies are vendored from.
+type upstream struct {
+	t    *testing.T
+	dir  string
+	repo *gogit.Repository
+}
+
+func newUpstream(t *testing.T, files map[string]string) *upstream {
+	dir := t.TempDir()
+	repo, err := gogit.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	u := &upstream{t, dir, repo}
+	u.commit(files)
+	return u
+}
+
+// commit writes the files, removes the removed ones, and commits them.
+func (u *upstream) commit(files map[string]string, removed ...string) {
+	worktree, err := u.repo.Worktree()
+	require.NoError(u.t, err)
+
+	for path, data := range files {
+		filename := filepath.Join(u.dir, path)
+		require.NoError(u.t, os.MkdirAll(filepath.Dir(filename), os.ModePerm))
+		require.NoError(u.t, os.WriteFile(filename, []byte(data), 0o644))
+		_, err := worktree.Add(path)
+		require.NoError(u.t, err)
+	}
+	for _, path := range removed {
+		_, err := worktree.Remove(path)
+		require.NoError(u.t, err)
+	}
+
+	_, err = worktree.Commit("commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@test", When: time.Now()},
+	})
+	require.NoError(u.t, err)
+}
+
+type testProject struct {
+	t         *testing.T
+	cache     *cache.Cache
+	spec      *vending.Spec
+	specLock  *vending.SpecLock
+	vendorDir string
+}
+
+func newTestProject(t *testing.T) *testProject {
+	spec := vending.NewSpec(nil)
+	spec.Deps = []*vending.Dependency{}
+	return &testProject{
+		t:         t,
+		cache:     cache.New(t.TempDir()),
+		spec:      spec,
+		specLock:  vending.NewSpecLock(nil),
+		vendorDir: filepath.Join(t.TempDir(), "vendor"),
+	}
+}
+
+func (p *testProject) addDependency(name, url string) *vending.Dependency {
+	dep := vending.NewDependency(url, "master")
+	dep.Name = name
+	dep.Dest = name
+	dep.Filters.AddExtension("proto")
+	p.spec.Deps = append(p.spec.Deps, dep)
+	return dep
+}
+
+func (p *testProject) install() *Report {
+	return p.run((*Installer).Install)
+}
+
+func (p *testProject) update() *Report {
+	return p.run((*Installer).Update)
+}
+
+// run runs the action, and commits its changes.
+func (p *testProject) run(action func(*Installer, context.Context) (*Report, error)) *Report {
+	ins := New(p.cache, p.spec, p.specLock).WithVendorDir(p.vendorDir)
+	report, err := action(ins, context.Background())
+	require.NoError(p.t, err)
+
+	tx := txn.New()
+	require.NoError(p.t, ins.Commit(tx))
+	require.NoError(p.t, tx.Commit())
+	return report
+}
+
+func (p *testProject) path(path string) string {
+	return filepath.Join(p.vendorDir, filepath.FromSlash(path))
+}
+
+func (p *testProject) stat(path string) os.FileInfo {
+	info, err := os.Stat(p.path(path))
+	require.NoError(p.t, err)
+	return info
+}
+
+func (p *testProject) read(path string) string {
+	data, err := os.ReadFile(p.path(path))
+	require.NoError(p.t, err)
+	return string(data)
+}
+
+func TestInstaller_Install_SkipsUnchanged(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a", "b.proto": "b"})
+	sut := newTestProject(t)
+	sut.addDependency("up", up.dir)
+
+	sut.install()
+	before := sut.stat("up/a.proto")
+
+	report := sut.install()
+
+	assert.Equal(t, StatusSkipped, report.Results[0].Status)
+	assert.Empty(t, report.Results[0].Replaced)
+	assert.True(t, os.SameFile(before, sut.stat("up/a.proto")))
+	assert.Equal(t, "b", sut.read("up/b.proto"))
+}
+
+func TestInstaller_Install_SkipsDependencyWithoutFiles(t *testing.T) {
+	up := newUpstream(t, map[string]string{"README.md": "readme"})
+	sut := newTestProject(t)
+	sut.addDependency("up", up.dir)
+
+	sut.install()
+	report := sut.install()
+
+	assert.Equal(t, StatusSkipped, report.Results[0].Status)
+	assert.Empty(t, report.Results[0].Lock.Files)
+}
+
+func TestInstaller_Install_WhenURLChanged_Reinstalls(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	other := newUpstream(t, map[string]string{"a.proto": "other"})
+	sut := newTestProject(t)
+	dep := sut.addDependency("up", up.dir)
+	sut.install()
+
+	// The commit of the lock is not in the other repository, so update.
+	dep.URL = other.dir
+	report := sut.update()
+
+	assert.Equal(t, StatusSucceeded, report.Results[0].Status)
+	assert.Equal(t, "other", sut.read("up/a.proto"))
+}
+
+func TestInstaller_Install_RestoresModifiedFiles(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a", "b.proto": "b"})
+	sut := newTestProject(t)
+	sut.addDependency("up", up.dir)
+
+	sut.install()
+	untouched := sut.stat("up/b.proto")
+	require.NoError(t, os.WriteFile(sut.path("up/a.proto"), []byte("modified"), 0o644))
+
+	report := sut.install()
+
+	assert.Equal(t, StatusSucceeded, report.Results[0].Status)
+	assert.Equal(t, []string{"up/a.proto"}, report.Results[0].Replaced)
+	assert.Equal(t, "a", sut.read("up/a.proto"))
+	assert.True(t, os.SameFile(untouched, sut.stat("up/b.proto")))
+}
+
+func TestInstaller_Update_RemovesDeletedFiles(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a", "nested/b.proto": "b"})
+	sut := newTestProject(t)
+	sut.addDependency("up", up.dir)
+	sut.install()
+
+	up.commit(map[string]string{"c.proto": "c"}, "nested/b.proto")
+	sut.update()
+
+	// Locking the dependency overwrites its previous lock, which still has to
+	// tell which files were vendored before.
+	assert.NoFileExists(t, sut.path("up/nested/b.proto"))
+	assert.NoDirExists(t, sut.path("up/nested"))
+	assert.Equal(t, "c", sut.read("up/c.proto"))
+	assert.Equal(t, "a", sut.read("up/a.proto"))
+}
+
+func TestInstaller_Install_RemovesFilesOfPrunedDependencies(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	sut := newTestProject(t)
+	sut.addDependency("one", up.dir)
+	sut.addDependency("two", up.dir)
+	sut.install()
+
+	sut.spec.Deps = sut.spec.Deps[:1]
+	sut.install()
+
+	assert.Equal(t, "a", sut.read("one/a.proto"))
+	assert.NoFileExists(t, sut.path("two/a.proto"))
+	assert.Len(t, sut.specLock.Deps, 1)
+}
+
+func TestInstaller_Install_RestoresShadowedFiles(t *testing.T) {
+	low := newUpstream(t, map[string]string{"a.proto": "low", "b.proto": "b"})
+	high := newUpstream(t, map[string]string{"a.proto": "high"})
+	sut := newTestProject(t)
+	sut.addDependency("low", low.dir).Dest = "shared"
+	sut.addDependency("high", high.dir).Dest = "shared"
+	sut.spec.Deps[1].Priority = 1
+	sut.install()
+	require.Equal(t, "high", sut.read("shared/a.proto"))
+	require.Equal(t, []string{"shared/a.proto"}, sut.specLock.Deps[0].Shadowed)
+
+	// The lock of the low priority dependency does not record the shadowed
+	// file, it still has to be imported again.
+	sut.spec.Deps = sut.spec.Deps[:1]
+	report := sut.install()
+
+	assert.Equal(t, StatusSucceeded, report.Results[0].Status)
+	assert.Equal(t, "low", sut.read("shared/a.proto"))
+	assert.Equal(t, "b", sut.read("shared/b.proto"))
+	assert.Empty(t, sut.specLock.Deps[0].Shadowed)
+}
+
+func TestInstaller_Install_WhenCollisionIsLost_OnlyEmitsFailed(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	other := newUpstream(t, map[string]string{"a.proto": "other"})
+	sut := newTestProject(t)
+	sut.addDependency("one", up.dir).Dest = "shared"
+	sut.addDependency("two", other.dir).Dest = "shared"
+
+	emitted := map[string][]string{}
+	ins := New(sut.cache, sut.spec, sut.specLock).WithVendorDir(sut.vendorDir).
+		WithSink(event.SinkFunc(func(e event.Event) {
+			switch e := e.(type) {
+			case event.DependencyLocked:
+				emitted[e.Dependency] = append(emitted[e.Dependency], "locked")
+			case event.DependencyFailed:
+				emitted[e.Dependency] = append(emitted[e.Dependency], "failed")
+			}
+		}))
+	_, err := ins.Install(context.Background())
+
+	assert.ErrorIs(t, err, vending.ErrOutputCollision)
+	assert.Equal(t, map[string][]string{"one": {"failed"}, "two": {"failed"}}, emitted)
+}
+
+func TestInstaller_Install_WhenFilesWereNotRecorded_RemovesUnrecordedFiles(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	head, err := up.repo.Head()
+	require.NoError(t, err)
+	sut := newTestProject(t)
+	sut.addDependency("up", up.dir)
+
+	// A lock written before the files of the locks were recorded.
+	lock := vending.NewDependencyLock(up.dir, head.Hash().String())
+	lock.Name = "up"
+	sut.specLock.AddDependencyLock(lock)
+	for _, path := range []string{"up/a.proto", "old/stale.proto"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(sut.path(path)), os.ModePerm))
+		require.NoError(t, os.WriteFile(sut.path(path), []byte("stale"), 0o644))
+	}
+
+	sut.install()
+
+	assert.Equal(t, "a", sut.read("up/a.proto"))
+	assert.NoFileExists(t, sut.path("old/stale.proto"))
+	assert.NoDirExists(t, sut.path("old"))
+	assert.NotEmpty(t, sut.specLock.Deps[0].Digest)
+}
+
+func TestInstaller_Install_KeepsUnrecordedFiles(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	sut := newTestProject(t)
+	sut.addDependency("up", up.dir)
+	sut.install()
+
+	require.NoError(t, os.WriteFile(sut.path("up/b.proto"), []byte("b"), 0o644))
+	require.NoError(t, os.WriteFile(sut.path("up/a.proto"), []byte("modified"), 0o644))
+	sut.install()
+
+	assert.Equal(t, "a", sut.read("up/a.proto"))
+	assert.Equal(t, "b", sut.read("up/b.proto"))
+}
+
+func TestInstaller_Update_WithSelection_KeepsTheOthers(t *testing.T) {
+	one := newUpstream(t, map[string]string{"a.proto": "a", "b.proto": "b"})
+	two := newUpstream(t, map[string]string{"c.proto": "c"})
+	sut := newTestProject(t)
+	selected := sut.addDependency("one", one.dir)
+	sut.addDependency("two", two.dir)
+	sut.install()
+	locked, _ := sut.specLock.FindByID("two")
+	lock := *locked
+	lock.Files = maps.Clone(locked.Files)
+	before := sut.stat("two/c.proto")
+
+	one.commit(map[string]string{}, "b.proto")
+	two.commit(map[string]string{"c.proto": "updated"})
+	sut.run(func(in *Installer, ctx context.Context) (*Report, error) {
+		return in.WithSelection([]*vending.Dependency{selected}).Update(ctx)
+	})
+
+	assert.NoFileExists(t, sut.path("one/b.proto"))
+	assert.Equal(t, "a", sut.read("one/a.proto"))
+	actual, _ := sut.specLock.FindByID("two")
+	assert.Equal(t, lock, *actual)
+	assert.True(t, os.SameFile(before, sut.stat("two/c.proto")))
+}
+
+func TestInstaller_Install_WithKeepGoing_InstallsTheOthers(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	sut := newTestProject(t)
+	sut.addDependency("missing", filepath.Join(t.TempDir(), "missing"))
+	sut.addDependency("up", up.dir)
+
+	ins := New(sut.cache, sut.spec, sut.specLock).WithVendorDir(sut.vendorDir).WithKeepGoing(true)
+	report, err := ins.Install(context.Background())
+	tx := txn.New()
+	require.NoError(t, ins.Commit(tx))
+	require.NoError(t, tx.Commit())
+
+	failed := &FailedError{}
+	require.ErrorAs(t, err, &failed)
+	assert.Equal(t, 1, failed.Failed)
+	assert.Equal(t, 2, failed.Total)
+	assert.ErrorIs(t, err, report.Results[0].Err)
+	assert.Equal(t, StatusFailed, report.Results[0].Status)
+	assert.Equal(t, StatusSucceeded, report.Results[1].Status)
+	assert.Equal(t, "a", sut.read("up/a.proto"))
+	assert.Len(t, sut.specLock.Deps, 1)
+	assert.Equal(t, "up", sut.specLock.Deps[0].Name)
+}
+
+func TestInstaller_Install_WithoutKeepGoing_InstallsNothing(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	sut := newTestProject(t)
+	sut.addDependency("missing", filepath.Join(t.TempDir(), "missing"))
+	sut.addDependency("up", up.dir)
+
+	ins := New(sut.cache, sut.spec, sut.specLock).WithVendorDir(sut.vendorDir)
+	_, err := ins.Install(context.Background())
+
+	assert.Error(t, err)
+	assert.Empty(t, sut.specLock.Deps)
+	assert.NoDirExists(t, sut.vendorDir)
+	assert.Error(t, ins.Commit(txn.New()))
+}
+
+func TestInstaller_Install_WhenInterrupted_KeepsEverything(t *testing.T) {
+	for name, cancelled := range map[string]func(cancel func()) event.Sink{
+		"before": func(cancel func()) event.Sink {
+			cancel()
+			return event.Discard
+		},
+		"while installing": func(cancel func()) event.Sink {
+			return event.SinkFunc(func(e event.Event) {
+				if _, ok := e.(event.DependencyStarted); ok {
+					cancel()
+				}
+			})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			up := newUpstream(t, map[string]string{"a.proto": "a"})
+			sut := newTestProject(t)
+			sut.addDependency("up", up.dir)
+			sut.install()
+			require.NoError(t, os.WriteFile(sut.path("up/a.proto"), []byte("modified"), 0o644))
+			lock := *sut.specLock.Deps[0]
+			lock.Files = maps.Clone(lock.Files)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ins := New(sut.cache, sut.spec, sut.specLock).WithVendorDir(sut.vendorDir).WithSink(cancelled(cancel))
+			_, err := ins.Install(ctx)
+
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Equal(t, "modified", sut.read("up/a.proto"))
+			assert.Equal(t, []*vending.DependencyLock{&lock}, sut.specLock.Deps)
+			entries, err := os.ReadDir(filepath.Dir(sut.vendorDir))
+			require.NoError(t, err)
+			require.Len(t, entries, 1, "the staging dir is removed")
+			assert.Equal(t, "vendor", entries[0].Name())
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package installer
+
+import (
+	"context"
+	"sync"
+)
+
+// limiter bounds how many dependencies are vendored at the same time, in
+// total and per host.
+type limiter struct {
+	jobs        chan struct{}
+	jobsPerHost int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func newLimiter(jobs, jobsPerHost int) *limiter {
+	return &limiter{
+		jobs:        make(chan struct{}, max(jobs, 1)),
+		jobsPerHost: jobsPerHost,
+		hosts:       map[string]chan struct{}{},
+	}
+}
+
+// acquire blocks until there is a free slot for the host, and returns the
+// function that frees it. The host slot is taken first, so dependencies
+// waiting for a busy host do not hold a slot other hosts could use. It stops
+// waiting when the context is done.
+func (l *limiter) acquire(ctx context.Context, host string) (func(), error) {
+	hostJobs := l.hostJobs(host)
+	if hostJobs != nil {
+		select {
+		case hostJobs <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {
+		if hostJobs != nil {
+			<-hostJobs
+		}
+	}
+
+	select {
+	case l.jobs <- struct{}{}:
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-l.jobs
+		release()
+	}, nil
+}
+
+func (l *limiter) hostJobs(host string) chan struct{} {
+	if l.jobsPerHost <= 0 || host == "" {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	jobs, ok := l.hosts[host]
+	if !ok {
+		jobs = make(chan struct{}, l.jobsPerHost)
+		l.hosts[host] = jobs
+	}
+	return jobs
+}
@@ -0,0 +1,100 @@
+package installer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acquireAsync acquires a slot for the host in the background, the channel
+// receives the outcome once acquire returns.
+func acquireAsync(ctx context.Context, l *limiter, host string) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(ctx, host)
+		done <- err
+	}()
+	return done
+}
+
+func assertBlocked(t *testing.T, done <-chan error) {
+	select {
+	case err := <-done:
+		t.Fatalf("acquire did not block: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func waitAcquire(t *testing.T, done <-chan error) error {
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("acquire is still blocked")
+		return nil
+	}
+}
+
+func TestLimiter_Jobs(t *testing.T) {
+	sut := newLimiter(2, 0)
+	release, err := sut.acquire(context.Background(), "one-host")
+	require.NoError(t, err)
+	_, err = sut.acquire(context.Background(), "other-host")
+	require.NoError(t, err)
+
+	done := acquireAsync(context.Background(), sut, "another-host")
+	assertBlocked(t, done)
+
+	release()
+	assert.NoError(t, waitAcquire(t, done))
+}
+
+func TestLimiter_JobsPerHost(t *testing.T) {
+	sut := newLimiter(10, 1)
+	release, err := sut.acquire(context.Background(), "some-host")
+	require.NoError(t, err)
+
+	done := acquireAsync(context.Background(), sut, "some-host")
+	assertBlocked(t, done)
+
+	// Other hosts are not limited by the busy one.
+	_, err = sut.acquire(context.Background(), "other-host")
+	require.NoError(t, err)
+
+	release()
+	assert.NoError(t, waitAcquire(t, done))
+}
+
+func TestLimiter_WhenCancelled_StopsWaiting(t *testing.T) {
+	for _, sut := range []*limiter{newLimiter(1, 0), newLimiter(10, 1)} {
+		_, err := sut.acquire(context.Background(), "some-host")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := acquireAsync(ctx, sut, "some-host")
+		assertBlocked(t, done)
+
+		cancel()
+		assert.ErrorIs(t, waitAcquire(t, done), context.Canceled)
+	}
+}
+
+func TestLimiter_WhenCancelled_ReleasesHostSlot(t *testing.T) {
+	sut := newLimiter(1, 1)
+	release, err := sut.acquire(context.Background(), "one-host")
+	require.NoError(t, err)
+
+	// Waits for a job, holding the slot of its host.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := acquireAsync(ctx, sut, "other-host")
+	assertBlocked(t, done)
+	cancel()
+	require.ErrorIs(t, waitAcquire(t, done), context.Canceled)
+
+	release()
+	done = acquireAsync(context.Background(), sut, "other-host")
+	assert.NoError(t, waitAcquire(t, done))
+}
@@ -0,0 +1,91 @@
+package installer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alevinval/vendor-go/pkg/vending"
+)
+
+// Status is the outcome of vendoring a dependency.
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusSkipped   Status = "skipped"
+	StatusFailed    Status = "failed"
+)
+
+// Result is the outcome of vendoring a single dependency. Lock is the lock of
+// the dependency after the operation, for failed dependencies it is the lock
+// they had before, if any. Err holds the cause of the failure. Duration is
+// how long vending the dependency took, not counting the time it waited for
+// other dependencies.
+type Result struct {
+	Dependency *vending.Dependency
+	Status     Status
+	Lock       *vending.DependencyLock
+	Err        error
+	Duration   time.Duration
+
+	// Replaced lists the paths, relative to the vendor directory, that Commit
+	// moved into it. Files that did not change are not listed.
+	Replaced []string
+
+	// previous is the lock the dependency had before, if any.
+	previous *vending.DependencyLock
+
+	// stagingDir is where the files of the dependency were vendored.
+	stagingDir string
+
+	// unchanged is set when the dependency was skipped, and its files are
+	// left as they are.
+	unchanged bool
+}
+
+// Report holds the result of every dependency, in spec order.
+type Report struct {
+	Results []*Result
+}
+
+// Failed returns the results of the dependencies that failed.
+func (r *Report) Failed() []*Result {
+	failed := []*Result{}
+	for _, result := range r.Results {
+		if result.Status == StatusFailed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns an error that wraps the cause of every failure, or nil when no
+// dependency failed.
+func (r *Report) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+
+	errs := []error{}
+	for _, result := range failed {
+		errs = append(errs, result.Err)
+	}
+	return &FailedError{Failed: len(failed), Total: len(r.Results), errs: errs}
+}
+
+// FailedError is returned when vendoring some of the dependencies failed, the
+// cause of each failure is in the Report, and can be unwrapped.
+type FailedError struct {
+	Failed int
+	Total  int
+	errs   []error
+}
+
+func (e *FailedError) Error() string {
+	return fmt.Sprintf("%d of %d dependencies failed", e.Failed, e.Total)
+}
+
+func (e *FailedError) Unwrap() []error {
+	return e.errs
+}
@@ -1,6 +1,8 @@
 package lock
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -8,13 +10,17 @@ import (
 	"github.com/rogpeppe/go-internal/lockedfile"
 )
 
+// ErrTimeout is returned when the lock cannot be acquired within the timeout.
+var ErrTimeout = errors.New("timed out")
+
 // Lock is used to ensure only one agent has permission to do certain operations.
 // It relies on creating files
 type Lock struct {
-	file   *lockedfile.File
-	path   string
-	warn   string
-	period time.Duration
+	file    *lockedfile.File
+	path    string
+	warn    string
+	period  time.Duration
+	timeout time.Duration
 
 	acquired bool
 }
@@ -41,10 +47,29 @@ func (l *Lock) WithPeriod(period time.Duration) *Lock {
 	return l
 }
 
+// WithTimeout configures how long lock acquisition waits before giving up,
+// zero waits as long as needed.
+func (l *Lock) WithTimeout(timeout time.Duration) *Lock {
+	l.timeout = timeout
+	return l
+}
+
 // Acquire attempts to lock the file. This operation blocks as long as needed
 // until it can be acquired.
-func (l *Lock) Acquire() (err error) {
-	if l.file, err = l.acquireLock(); err != nil {
+func (l *Lock) Acquire() error {
+	return l.AcquireContext(context.Background())
+}
+
+// AcquireContext attempts to lock the file. This operation blocks until it can
+// be acquired, the timeout expires, or the context is done.
+func (l *Lock) AcquireContext(ctx context.Context) (err error) {
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, l.timeout, fmt.Errorf("%w after %s", ErrTimeout, l.timeout))
+		defer cancel()
+	}
+
+	if l.file, err = l.acquireLock(ctx); err != nil {
 		return fmt.Errorf("cannot create lockfile: %w", err)
 	} else {
 		l.acquired = true
@@ -62,16 +87,17 @@ func (l *Lock) Release() error {
 
 // acquireLock creates the lockfile in a go-routine and waits for the process to complete.
 // If the process takes too long and warning is enabled, it will print a warning message.
-func (l *Lock) acquireLock() (*lockedfile.File, error) {
-	fileCh := make(chan *lockedfile.File)
-	errCh := make(chan error)
+func (l *Lock) acquireLock(ctx context.Context) (*lockedfile.File, error) {
+	fileCh := make(chan *lockedfile.File, 1)
+	errCh := make(chan error, 1)
 	go createLockFile(l.path, fileCh, errCh)
-	return waitForAcquire(fileCh, errCh, l.period, l.warn)
+	return waitForAcquire(ctx, fileCh, errCh, l.period, l.warn)
 }
 
 // waitForAcquire polls the channel and displays a warning message in case the lock
 // operation takes longer than expected.
 func waitForAcquire(
+	ctx context.Context,
 	fileIn <-chan *lockedfile.File,
 	errIn <-chan error,
 	period time.Duration,
@@ -89,6 +115,17 @@ func waitForAcquire(
 			return nil, e
 		case lock := <-fileIn:
 			return lock, nil
+		case <-ctx.Done():
+			// The lockfile is still being created in the background, release
+			// it as soon as it is acquired.
+			go func() {
+				select {
+				case lock := <-fileIn:
+					lock.Close()
+				case <-errIn:
+				}
+			}()
+			return nil, context.Cause(ctx)
 		}
 	}
 }
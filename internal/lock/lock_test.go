@@ -1,6 +1,7 @@
 package lock
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -55,3 +56,44 @@ func tryAcquire(t *testing.T, l *Lock) chan struct{} {
 
 	return out
 }
+
+func TestLock_AcquireContext_StopsWaitingWhenDone(t *testing.T) {
+	defer cleanUp("LOCK")
+
+	one := New("LOCK")
+	two := New("LOCK")
+
+	err := one.Acquire()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = two.AcquireContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = one.Release()
+	assert.NoError(t, err)
+
+	three := New("LOCK")
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = three.AcquireContext(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, three.Release())
+}
+
+func TestLock_WithTimeout(t *testing.T) {
+	defer cleanUp("LOCK")
+
+	one := New("LOCK")
+	two := New("LOCK").WithTimeout(50 * time.Millisecond)
+
+	err := one.Acquire()
+	assert.NoError(t, err)
+	defer one.Release()
+
+	err = two.Acquire()
+	assert.ErrorIs(t, err, ErrTimeout)
+}
@@ -0,0 +1,229 @@
+// Package txn groups filesystem changes so that either all of them are
+// applied, or none of them is.
+package txn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Transaction keeps track of the changes that have been applied, so they can
+// be undone on Rollback. The previous contents of the replaced and removed
+// files are kept aside until Commit.
+type Transaction struct {
+	undo     []func() error
+	backups  []string
+	onCommit []func()
+}
+
+// New allocates an empty Transaction.
+func New() *Transaction {
+	return &Transaction{}
+}
+
+// ReplaceFile renames src to dst, creating the parent directories of dst, and
+// moving aside the current dst, if any, so it can be restored on Rollback.
+// Directories that are created are not removed on Rollback.
+func (t *Transaction) ReplaceFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return fmt.Errorf("cannot create %q: %w", filepath.Dir(dst), err)
+	}
+	return t.replace(src, dst)
+}
+
+func (t *Transaction) replace(src, dst string) error {
+	backup := ""
+	if _, err := os.Stat(dst); err == nil {
+		backup, err = siblingName(dst, "backup")
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(dst, backup); err != nil {
+			return fmt.Errorf("cannot move aside %q: %w", dst, err)
+		}
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		if backup != "" {
+			err = errors.Join(err, os.Rename(backup, dst))
+		}
+		return fmt.Errorf("cannot rename %q to %q: %w", src, dst, err)
+	}
+
+	t.undo = append(t.undo, func() error {
+		if err := os.RemoveAll(dst); err != nil {
+			return err
+		}
+		if backup == "" {
+			return nil
+		}
+		return os.Rename(backup, dst)
+	})
+	if backup != "" {
+		t.backups = append(t.backups, backup)
+	}
+	return nil
+}
+
+// WriteFile atomically replaces the contents of filename, the previous
+// contents are restored on Rollback.
+func (t *Transaction) WriteFile(filename string, data []byte) error {
+	previous, err := os.ReadFile(filename)
+	existed := err == nil
+
+	if err := WriteFileAtomic(filename, data); err != nil {
+		return err
+	}
+
+	t.undo = append(t.undo, func() error {
+		if existed {
+			return WriteFileAtomic(filename, previous)
+		}
+		return os.Remove(filename)
+	})
+	return nil
+}
+
+// RemoveFile moves filename aside, so it can be restored on Rollback. A file
+// that does not exist is not an error.
+func (t *Transaction) RemoveFile(filename string) error {
+	if _, err := os.Lstat(filename); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	backup, err := siblingName(filename, "backup")
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(filename, backup); err != nil {
+		return fmt.Errorf("cannot remove %q: %w", filename, err)
+	}
+
+	t.undo = append(t.undo, func() error {
+		return os.Rename(backup, filename)
+	})
+	t.backups = append(t.backups, backup)
+	return nil
+}
+
+// OnCommit registers fn to run once the transaction is committed, it does
+// not run on Rollback.
+func (t *Transaction) OnCommit(fn func()) {
+	t.onCommit = append(t.onCommit, fn)
+}
+
+// Rollback undoes, in reverse order, every change applied so far.
+func (t *Transaction) Rollback() error {
+	errs := []error{}
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		if err := t.undo[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.undo = nil
+	t.backups = nil
+	t.onCommit = nil
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("cannot rollback: %w", err)
+	}
+	return nil
+}
+
+// Commit makes the changes permanent, discarding the previous contents.
+func (t *Transaction) Commit() error {
+	errs := []error{}
+	for _, backup := range t.backups {
+		if err := os.RemoveAll(backup); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, fn := range t.onCommit {
+		fn()
+	}
+	t.undo = nil
+	t.backups = nil
+	t.onCommit = nil
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("cannot remove backups: %w", err)
+	}
+	return nil
+}
+
+// RemoveEmptyDirs removes the parent directories of the files, relative to
+// root, that are empty. Directories that are not empty, and root, are kept.
+func RemoveEmptyDirs(root string, files []string) {
+	for _, path := range files {
+		dir := filepath.Dir(filepath.FromSlash(path))
+		for dir != "." && dir != string(filepath.Separator) {
+			// Fails, and stops, when the directory is not empty.
+			if err := os.Remove(filepath.Join(root, dir)); err != nil {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+	}
+}
+
+// WriteFileAtomic writes data into a temporary file next to filename, and
+// renames it over filename, so readers never observe a partial write. The
+// mode of an existing file is preserved.
+func WriteFileAtomic(filename string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file: %w", err)
+	}
+	tmp := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("cannot write %q: %w", filename, err)
+	}
+	return nil
+}
+
+// MkdirTemp creates a temporary directory next to path, creating the parent
+// directories if needed. Being on the same filesystem as path, it can be
+// renamed over it.
+func MkdirTemp(path, suffix string) (string, error) {
+	parent := filepath.Dir(filepath.Clean(path))
+	if err := os.MkdirAll(parent, os.ModePerm); err != nil {
+		return "", fmt.Errorf("cannot create %q: %w", parent, err)
+	}
+	dir, err := os.MkdirTemp(parent, "."+filepath.Base(filepath.Clean(path))+"."+suffix+"-*")
+	if err != nil {
+		return "", fmt.Errorf("cannot create temporary dir: %w", err)
+	}
+	return dir, nil
+}
+
+func siblingName(path, suffix string) (string, error) {
+	dir, err := MkdirTemp(path, suffix)
+	if err != nil {
+		return "", err
+	}
+	// Only the unique name is needed, the path is renamed into it.
+	if err := os.Remove(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,124 @@
+package txn
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, filename, data string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(filename), os.ModePerm))
+	require.NoError(t, os.WriteFile(filename, []byte(data), 0o644))
+}
+
+func readFile(t *testing.T, filename string) string {
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func entries(t *testing.T, dir string) []string {
+	list, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, entry := range list {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestTransaction_WriteFile_Rollback(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "existing")
+	created := filepath.Join(root, "created")
+	writeFile(t, existing, "before")
+
+	sut := New()
+	assert.NoError(t, sut.WriteFile(existing, []byte("after")))
+	assert.NoError(t, sut.WriteFile(created, []byte("after")))
+	assert.Equal(t, "after", readFile(t, existing))
+	assert.Equal(t, "after", readFile(t, created))
+
+	assert.NoError(t, sut.Rollback())
+	assert.Equal(t, "before", readFile(t, existing))
+	assert.NoFileExists(t, created)
+}
+
+func TestTransaction_RemoveFile(t *testing.T) {
+	root := t.TempDir()
+	kept := filepath.Join(root, "a", "kept")
+	removed := filepath.Join(root, "a", "removed")
+	writeFile(t, kept, "kept")
+	writeFile(t, removed, "removed")
+
+	sut := New()
+	assert.NoError(t, sut.RemoveFile(removed))
+	assert.NoError(t, sut.RemoveFile(filepath.Join(root, "missing")))
+	assert.NoFileExists(t, removed)
+
+	assert.NoError(t, sut.Rollback())
+	assert.Equal(t, "removed", readFile(t, removed))
+
+	assert.NoError(t, sut.RemoveFile(removed))
+	assert.NoError(t, sut.Commit())
+	assert.Equal(t, []string{"kept"}, entries(t, filepath.Join(root, "a")))
+}
+
+func TestTransaction_ReplaceFile(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "vendor", "existing")
+	created := filepath.Join(root, "vendor", "a", "created")
+	writeFile(t, existing, "before")
+	writeFile(t, filepath.Join(root, "staging", "existing"), "after")
+	writeFile(t, filepath.Join(root, "staging", "created"), "after")
+
+	sut := New()
+	assert.NoError(t, sut.ReplaceFile(filepath.Join(root, "staging", "existing"), existing))
+	assert.NoError(t, sut.ReplaceFile(filepath.Join(root, "staging", "created"), created))
+	assert.Equal(t, "after", readFile(t, existing))
+	assert.Equal(t, "after", readFile(t, created))
+
+	assert.NoError(t, sut.Rollback())
+	assert.Equal(t, "before", readFile(t, existing))
+	assert.NoFileExists(t, created)
+}
+
+func TestTransaction_OnCommit(t *testing.T) {
+	calls := 0
+
+	sut := New()
+	sut.OnCommit(func() { calls++ })
+	assert.NoError(t, sut.Rollback())
+	assert.NoError(t, sut.Commit())
+	assert.Equal(t, 0, calls)
+
+	sut.OnCommit(func() { calls++ })
+	assert.NoError(t, sut.Commit())
+	assert.Equal(t, 1, calls)
+}
+
+func TestRemoveEmptyDirs(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a", "kept"), "kept")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b", "c"), os.ModePerm))
+
+	RemoveEmptyDirs(root, []string{"a/b/c/removed", "removed"})
+
+	assert.Equal(t, []string{"kept"}, entries(t, filepath.Join(root, "a")))
+	assert.DirExists(t, root)
+}
+
+func TestWriteFileAtomic_PreservesMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(filename, []byte("before"), 0o600))
+
+	assert.NoError(t, WriteFileAtomic(filename, []byte("after")))
+
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	assert.Equal(t, "after", readFile(t, filename))
+}
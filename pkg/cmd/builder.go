@@ -1,9 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
-	"github.com/alevinval/vendor-go/internal/control"
+	"github.com/alevinval/vendor-go/pkg/control"
 	"github.com/alevinval/vendor-go/pkg/log"
 	"github.com/alevinval/vendor-go/pkg/vending"
 	"github.com/spf13/cobra"
@@ -29,114 +35,336 @@ type builder struct {
 }
 
 func (b *builder) buildCobra() *cobra.Command {
-	controller := control.New(
-		control.WithPreset(b.preset),
-	)
-
-	rootCmd := newRootCmd(b.commandName, b.debugFlag)
-	rootCmd.AddCommand(newInitCmd(controller))
-	rootCmd.AddCommand(newAddCmd(controller))
-	rootCmd.AddCommand(newInstallCmd(controller))
-	rootCmd.AddCommand(newUpdateCmd(controller))
-	rootCmd.AddCommand(newCleanCacheCmd(controller))
+	out := newOutput()
+	paths := &pathFlags{}
+
+	// The controller is allocated once the flags are parsed, since it depends
+	// on the paths given on the command line.
+	controller := func() *control.Controller {
+		return control.New(
+			control.WithPreset(b.preset),
+			control.WithWorkDir(paths.dir),
+			control.WithSpecFile(paths.spec),
+			control.WithLockFile(paths.lock),
+			control.WithDiscovery(),
+			control.WithEventSink(out.progress),
+		)
+	}
+
+	rootCmd := newRootCmd(b.commandName, b.debugFlag, paths, out)
+	rootCmd.AddCommand(newInitCmd(controller, out))
+	rootCmd.AddCommand(newAddCmd(controller, out))
+	rootCmd.AddCommand(newRemoveCmd(controller, out))
+	rootCmd.AddCommand(newPinCmd(controller, out))
+	rootCmd.AddCommand(newUnpinCmd(controller, out))
+	rootCmd.AddCommand(newInstallCmd(controller, out))
+	rootCmd.AddCommand(newUpdateCmd(controller, out))
+	rootCmd.AddCommand(newValidateCmd(controller, out))
+	rootCmd.AddCommand(newVerifyCmd(controller, out))
+	rootCmd.AddCommand(newMigrateCmd(controller, out))
+	rootCmd.AddCommand(newCleanCacheCmd(controller, out))
 	return rootCmd
 }
 
-func newRootCmd(commandName string, debugFlag *bool) *cobra.Command {
+// pathFlags locate the spec, the lockfile and the vendor directory, instead of
+// the working directory and the filenames of the preset.
+type pathFlags struct {
+	dir  string
+	spec string
+	lock string
+}
+
+func (p *pathFlags) check() error {
+	if p.dir == "" {
+		return nil
+	}
+	info, err := os.Stat(p.dir)
+	if err != nil {
+		return fmt.Errorf("cannot use -C %q: %w", p.dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("cannot use -C %q: not a directory", p.dir)
+	}
+	return nil
+}
+
+func newRootCmd(commandName string, debugFlag *bool, paths *pathFlags, out *output) *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   commandName,
 		Short: fmt.Sprintf("%s is a flexible and customizable vending tool (%s)", commandName, vending.VERSION),
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Errors are logged by Execute, together with a hint to fix them.
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if *debugFlag {
 				log.Level.SetLevel(zapcore.DebugLevel)
 			}
+			if err := out.check(); err != nil {
+				return err
+			}
+			if err := paths.check(); err != nil {
+				return err
+			}
+			if out.format == OutputJSON {
+				log.UseStderr()
+			}
+			cmd.SetContext(withInterrupt(cmd.Context()))
+			return nil
 		},
 	}
 	rootCmd.PersistentFlags().BoolVarP(debugFlag, "debug", "d", false, "enable debug logging")
+	rootCmd.PersistentFlags().StringVarP(&out.format, "output", "o", OutputText, "output format, json prints a result document on stdout and logs on stderr")
+	rootCmd.PersistentFlags().BoolVar(&out.showProgress, "progress", true, "draw progress bars when stderr is a terminal")
+	rootCmd.PersistentFlags().StringVarP(&paths.dir, "directory", "C", "", "run as if started in this directory")
+	rootCmd.PersistentFlags().StringVar(&paths.spec, "spec", "", "spec file to use instead of the one of the preset, relative to -C")
+	rootCmd.PersistentFlags().StringVar(&paths.lock, "lock", "", "lockfile to use instead of the one of the preset, relative to -C")
 	return rootCmd
 }
 
-func newInitCmd(controller *control.Controller) *cobra.Command {
+// withInterrupt returns a context that is cancelled on the first interrupt, so
+// the running command can stop and roll back. A second interrupt terminates
+// the process right away.
+func withInterrupt(ctx context.Context) context.Context {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx
+}
+
+// controllerFunc allocates the controller when a command runs.
+type controllerFunc = func() *control.Controller
+
+func newInitCmd(controller controllerFunc, out *output) *cobra.Command {
 	return &cobra.Command{
 		Use:   "init",
 		Short: "initializes the current directory",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := controller.Init()
-			if err != nil {
-				log.S().Errorf("%s", err)
-			}
-		},
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
+			return controller().Init()
+		}),
 	}
 }
 
-func newAddCmd(controller *control.Controller) *cobra.Command {
+func newAddCmd(controller controllerFunc, out *output) *cobra.Command {
 	targets := []string{}
 	ignores := []string{}
 	extensions := []string{}
+	name := ""
+	ref := ""
+	dest := ""
+	strip := ""
 
 	addCmd := &cobra.Command{
-		Use:   "add [url] [branch]",
+		Use:   "add <url> [branch]",
 		Short: "Add a new dependency to the spec",
-		Args:  cobra.ExactArgs(2),
-		Run: func(cmd *cobra.Command, args []string) {
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
 			url := args[0]
-			branch := args[1]
+			branch := ""
+			if len(args) > 1 {
+				branch = args[1]
+			} else if ref == "" {
+				return fmt.Errorf("cannot add %s: specify a branch or --ref", url)
+			}
 
-			filters := vending.NewFilters().
+			dep := vending.NewDependency(url, branch)
+			dep.Name = name
+			dep.Ref = ref
+			dep.Dest = dest
+			dep.Strip = strip
+			dep.Filters.
 				AddTarget(targets...).
 				AddIgnore(ignores...).
 				AddExtension(extensions...)
 
-			err := controller.AddDependency(url, branch, filters)
-
-			if err != nil {
-				log.S().Errorf("%s", err)
-			}
-		},
+			return controller().Add(dep)
+		}),
 	}
 
 	addCmd.PersistentFlags().StringArrayVarP(&targets, "targets", "t", []string{}, "targeted paths")
 	addCmd.PersistentFlags().StringArrayVarP(&ignores, "ignores", "i", []string{}, "ignored paths")
 	addCmd.PersistentFlags().StringArrayVarP(&extensions, "ext", "e", []string{}, "targeted file extensions paths")
+	addCmd.PersistentFlags().StringVar(&name, "name", "", "name that identifies the dependency, defaults to the url")
+	addCmd.PersistentFlags().StringVar(&ref, "ref", "", "branch, tag, commit or semver constraint to vendor instead of the branch")
+	addCmd.PersistentFlags().StringVar(&dest, "dest", "", "directory, relative to the vendor dir, where files are copied")
+	addCmd.PersistentFlags().StringVar(&strip, "strip", "", "leading directory removed from the vendored paths")
 
 	return addCmd
 }
 
-func newInstallCmd(controller *control.Controller) *cobra.Command {
+func newRemoveCmd(controller controllerFunc, out *output) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove [url|name]",
+		Short: "Removes a dependency from the spec and the lockfile, and deletes its vendored files",
+		Args:  cobra.ExactArgs(1),
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
+			removed, err := controller().Remove(args[0])
+			res.setRemoved(removed)
+			return err
+		}),
+	}
+}
+
+func newPinCmd(controller controllerFunc, out *output) *cobra.Command {
+	opts := control.PinOptions{}
+
+	pinCmd := &cobra.Command{
+		Use:   "pin [url|name]",
+		Short: "Pins a dependency, so update keeps it at its locked commit",
+		Args:  cobra.ExactArgs(1),
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
+			return controller().Pin(args[0], opts)
+		}),
+	}
+
+	pinCmd.PersistentFlags().StringVar(&opts.Reason, "reason", "", "why the dependency is pinned")
+	pinCmd.PersistentFlags().StringVar(&opts.Until, "until", "", "date, as YYYY-MM-DD, after which update warns that the pin has to be revisited")
+
+	return pinCmd
+}
+
+func newUnpinCmd(controller controllerFunc, out *output) *cobra.Command {
 	return &cobra.Command{
+		Use:   "unpin [url|name]",
+		Short: "Unpins a dependency, so update moves it to the latest commit again",
+		Args:  cobra.ExactArgs(1),
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
+			return controller().Unpin(args[0])
+		}),
+	}
+}
+
+func newInstallCmd(controller controllerFunc, out *output) *cobra.Command {
+	frozen, _ := strconv.ParseBool(os.Getenv("VENDING_FROZEN"))
+
+	concurrency := control.ConcurrencyOptions{}
+	keepGoing := false
+	lockTimeout := time.Duration(0)
+	all := false
+
+	installCmd := &cobra.Command{
 		Use:   "install",
 		Short: "Installs dependencies respecting the lockfile",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := controller.Install()
-			if err != nil {
-				log.S().Errorf("%s", err)
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
+			opts := control.InstallOptions{
+				ConcurrencyOptions: concurrency,
+				Frozen:             frozen,
+				KeepGoing:          keepGoing,
+				LockTimeout:        lockTimeout,
 			}
-		},
+			return out.withProgress(func() error {
+				if all {
+					result, err := controller().InstallWorkspace(cmd.Context(), opts)
+					res.setWorkspace(result)
+					return err
+				}
+				result, err := controller().Install(cmd.Context(), opts)
+				res.setResult(result)
+				return err
+			})
+		}),
+	}
+
+	installCmd.PersistentFlags().BoolVar(&frozen, "frozen", frozen, "fail when the lockfile is out of date with the spec, also enabled with VENDING_FROZEN=1")
+	installCmd.PersistentFlags().BoolVar(&all, "all", false, "install every member of the workspace declared by the nearest "+vending.WorkspaceFilename)
+	addConcurrencyFlags(installCmd, &concurrency)
+	addKeepGoingFlag(installCmd, &keepGoing)
+	addLockTimeoutFlag(installCmd, &lockTimeout)
+
+	return installCmd
+}
+
+func newUpdateCmd(controller controllerFunc, out *output) *cobra.Command {
+	concurrency := control.ConcurrencyOptions{}
+	keepGoing := false
+	lockTimeout := time.Duration(0)
+	failOnExpiredPins := false
+
+	updateCmd := &cobra.Command{
+		Use:   "update [url|name...]",
+		Short: "update dependencies to the latest commit from the branch of the spec, or only the given ones",
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
+			return out.withProgress(func() error {
+				result, err := controller().Update(cmd.Context(), control.UpdateOptions{
+					ConcurrencyOptions: concurrency,
+					Dependencies:       args,
+					KeepGoing:          keepGoing,
+					LockTimeout:        lockTimeout,
+					FailOnExpiredPins:  failOnExpiredPins,
+				})
+				res.setResult(result)
+				return err
+			})
+		}),
 	}
+
+	updateCmd.PersistentFlags().BoolVar(&failOnExpiredPins, "fail-on-expired-pins", false, "fail when a pin is past its --until date, instead of warning")
+	addConcurrencyFlags(updateCmd, &concurrency)
+	addKeepGoingFlag(updateCmd, &keepGoing)
+	addLockTimeoutFlag(updateCmd, &lockTimeout)
+
+	return updateCmd
+}
+
+func addKeepGoingFlag(cmd *cobra.Command, keepGoing *bool) {
+	cmd.PersistentFlags().BoolVarP(keepGoing, "keep-going", "k", false, "vendor every dependency that succeeded even when others fail, exits with non-zero code if any failed")
 }
 
-func newUpdateCmd(controller *control.Controller) *cobra.Command {
+func addLockTimeoutFlag(cmd *cobra.Command, lockTimeout *time.Duration) {
+	cmd.PersistentFlags().DurationVar(lockTimeout, "lock-timeout", DefaultLockTimeout, "how long to wait for other instances to release the cache, 0 waits forever")
+}
+
+func addConcurrencyFlags(cmd *cobra.Command, opts *control.ConcurrencyOptions) {
+	cmd.PersistentFlags().IntVarP(&opts.Jobs, "jobs", "j", 0, "number of dependencies vendored at the same time, defaults to the preset")
+	cmd.PersistentFlags().IntVar(&opts.JobsPerHost, "jobs-per-host", 0, "number of dependencies fetched from the same host at the same time, defaults to the preset")
+}
+
+func newValidateCmd(controller controllerFunc, out *output) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "checks the spec for mistakes, exits with non-zero code when invalid",
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
+			diags, err := controller().Validate()
+			res.setDiagnostics(diags)
+			return err
+		}),
+	}
+}
+
+func newVerifyCmd(controller controllerFunc, out *output) *cobra.Command {
 	return &cobra.Command{
-		Use:   "update",
-		Short: "update dependencies to the latest commit from the branch of the spec",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := controller.Update()
-			if err != nil {
-				log.S().Errorf("%s", err)
+		Use:   "verify",
+		Short: "checks the vendored files against the lockfile digests, exits with non-zero code on mismatch",
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
+			report, err := controller().Verify()
+			res.setVerify(report)
+			return err
+		}),
+	}
+}
+
+func newMigrateCmd(controller controllerFunc, out *output) *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "rewrites the spec and lockfile to the schema of the current version",
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
+			migrated, err := controller().Migrate()
+			if migrated != nil {
+				res.addMigration("spec", migrated.Spec)
+				res.addMigration("lock", migrated.Lock)
 			}
-		},
+			return err
+		}),
 	}
 }
 
-func newCleanCacheCmd(controller *control.Controller) *cobra.Command {
+func newCleanCacheCmd(controller controllerFunc, out *output) *cobra.Command {
 	return &cobra.Command{
 		Use:   "cleancache",
 		Short: "resets the repository cache",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := controller.CleanCache()
-			if err != nil {
-				log.S().Errorf("%s", err)
-			}
-		},
+		RunE: out.run(func(cmd *cobra.Command, args []string, res *result) error {
+			return controller().CleanCache()
+		}),
 	}
 }
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alevinval/vendor-go/pkg/log"
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// DefaultLockTimeout is how long install and update wait for other instances
+// to release the cache, unless --lock-timeout says otherwise.
+const DefaultLockTimeout = 5 * time.Minute
+
+// Exit codes of the CLI, they are documented in the README and must not
+// change once released.
+const (
+	ExitOK             = 0
+	ExitFailure        = 1
+	ExitSpecNotFound   = 2
+	ExitSpecInvalid    = 3
+	ExitLockOutOfDate  = 4
+	ExitNetwork        = 5
+	ExitVerifyMismatch = 6
+	ExitLockTimeout    = 7
+	ExitInterrupted    = 130
+)
+
+// errorKind maps an error to its exit code and hint, "{cmd}" in the hint is
+// replaced by the name of the command.
+type errorKind struct {
+	err  error
+	code int
+	hint string
+}
+
+// errorKinds is sorted by precedence, the first one that matches the error
+// decides the exit code.
+var errorKinds = []errorKind{
+	{context.Canceled, ExitInterrupted, ""},
+	{vending.ErrSpecNotFound, ExitSpecNotFound, "run `{cmd} init` to create it, or point {cmd} to it with -C or --spec"},
+	{vending.ErrWorkspaceNotFound, ExitSpecNotFound, "create a " + vending.WorkspaceFilename + " that lists the members, at the root of the workspace"},
+	{vending.ErrOutputCollision, ExitSpecInvalid, "use dest to vendor the dependencies into different directories, or give a higher priority to the one that should win"},
+	{vending.ErrSpecInvalid, ExitSpecInvalid, "fix the problems reported above, `{cmd} validate` checks the spec without installing"},
+	{vending.ErrLockOutOfDate, ExitLockOutOfDate, "run `{cmd} update` and commit the lockfile"},
+	{vending.ErrLockTimeout, ExitLockTimeout, "another instance of {cmd} is using the cache, wait for it to finish or raise --lock-timeout"},
+	{vending.ErrPinExpired, ExitFailure, "revisit the pins reported above, `{cmd} unpin` them or `{cmd} pin` them again with a later --until"},
+	{vending.ErrNetwork, ExitNetwork, "check the url of the dependency, your network connection and your git credentials"},
+	{vending.ErrUnvendoredFiles, ExitVerifyMismatch, "remove the added files, no dependency vendors them, and run `{cmd} install` to restore the other vendored files"},
+	{vending.ErrVerifyMismatch, ExitVerifyMismatch, "run `{cmd} install` to restore the vendored files, or `{cmd} update` if the changes are intended"},
+}
+
+// ExitCode returns the exit code that corresponds to the error.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if kind, ok := findErrorKind(err); ok {
+		return kind.code
+	}
+	return ExitFailure
+}
+
+// Hint returns an actionable suggestion to fix the error, or an empty string
+// when there is none. commandName is used to suggest commands to run.
+func Hint(err error, commandName string) string {
+	kind, ok := findErrorKind(err)
+	if !ok || kind.hint == "" {
+		return ""
+	}
+	return strings.ReplaceAll(kind.hint, "{cmd}", commandName)
+}
+
+// Execute runs the command, logging the error, if any, together with a hint
+// to fix it. It returns the code the process should exit with.
+func Execute(cmd *cobra.Command) int {
+	err := cmd.Execute()
+	if err == nil {
+		return ExitOK
+	}
+
+	log.S().Errorf("%s", err)
+	if hint := Hint(err, cmd.Name()); hint != "" {
+		log.S().Infof("%s %s", color.CyanString("hint:"), hint)
+	}
+	return ExitCode(err)
+}
+
+func findErrorKind(err error) (errorKind, bool) {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind, true
+		}
+	}
+	return errorKind{}, false
+}
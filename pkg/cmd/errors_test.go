@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitOK, ExitCode(nil))
+	assert.Equal(t, ExitFailure, ExitCode(errors.New("some error")))
+	assert.Equal(t, ExitSpecNotFound, ExitCode(fmt.Errorf("cannot load spec: %w", vending.ErrSpecNotFound)))
+	assert.Equal(t, ExitSpecInvalid, ExitCode(fmt.Errorf("cannot load spec: %w", vending.ErrSpecInvalid)))
+	assert.Equal(t, ExitSpecInvalid, ExitCode(fmt.Errorf("cannot install: %w", vending.ErrOutputCollision)))
+	assert.Equal(t, ExitLockOutOfDate, ExitCode(vending.ErrLockOutOfDate))
+	assert.Equal(t, ExitNetwork, ExitCode(errors.Join(errors.New("some error"), vending.ErrNetwork)))
+	assert.Equal(t, ExitVerifyMismatch, ExitCode(vending.ErrVerifyMismatch))
+	assert.Equal(t, ExitLockTimeout, ExitCode(vending.ErrLockTimeout))
+	assert.Equal(t, ExitFailure, ExitCode(fmt.Errorf("%w: 1 pin(s) expired", vending.ErrPinExpired)))
+	assert.Equal(t, ExitInterrupted, ExitCode(fmt.Errorf("interrupted: %w", context.Canceled)))
+}
+
+func TestHint(t *testing.T) {
+	assert.Equal(t, "", Hint(errors.New("some error"), "some-cmd"))
+	assert.Equal(t, "run `some-cmd update` and commit the lockfile", Hint(vending.ErrLockOutOfDate, "some-cmd"))
+}
+
+func TestHint_WhenFilesAreAdded(t *testing.T) {
+	err := fmt.Errorf("%w: some-dir, it has %w", vending.ErrVerifyMismatch, vending.ErrUnvendoredFiles)
+
+	assert.Equal(t, ExitVerifyMismatch, ExitCode(err))
+	assert.Equal(t, "remove the added files, no dependency vendors them, and run `some-cmd install` to restore the other vendored files", Hint(err, "some-cmd"))
+	assert.Equal(t, "run `some-cmd install` to restore the vendored files, or `some-cmd update` if the changes are intended", Hint(vending.ErrVerifyMismatch, "some-cmd"))
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/alevinval/vendor-go/pkg/control"
+	"github.com/alevinval/vendor-go/pkg/log"
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap/zapcore"
+)
+
+// Output formats supported by the --output flag.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// output decides how the commands report what they did. With OutputJSON,
+// every command prints one result document on stdout, while the logs go to
+// stderr.
+type output struct {
+	format       string
+	stdout       io.Writer
+	showProgress bool
+	progress     *progress
+}
+
+func newOutput() *output {
+	return &output{
+		format:       OutputText,
+		stdout:       os.Stdout,
+		showProgress: true,
+		progress:     newProgress(os.Stderr),
+	}
+}
+
+func (o *output) check() error {
+	switch o.format {
+	case OutputText, OutputJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown output %q, use %q or %q", o.format, OutputText, OutputJSON)
+	}
+}
+
+// run wraps the function of a command, so its result is printed when the
+// output is OutputJSON, whether it fails or not.
+func (o *output) run(fn func(cmd *cobra.Command, args []string, res *result) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		res := &result{Command: cmd.Name()}
+		start := time.Now()
+		err := fn(cmd, args, res)
+		if o.format != OutputJSON {
+			return err
+		}
+
+		res.DurationMs = time.Since(start).Milliseconds()
+		res.OK = err == nil
+		res.ExitCode = ExitCode(err)
+		if err != nil {
+			res.Error = err.Error()
+			res.Hint = Hint(err, cmd.Root().Name())
+		}
+
+		enc := json.NewEncoder(o.stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(res); encErr != nil && err == nil {
+			return fmt.Errorf("cannot write output: %w", encErr)
+		}
+		return err
+	}
+}
+
+// withProgress runs fn drawing progress bars on stderr, when it is a terminal
+// and the output is OutputText. Info logs are hidden meanwhile, the progress
+// bars replace them.
+func (o *output) withProgress(fn func() error) error {
+	if o.format != OutputText || !o.showProgress || !isTerminal(os.Stderr) {
+		return fn()
+	}
+
+	level := log.Level.Level()
+	if level < zapcore.InfoLevel {
+		// Debug logs would be drawn over by the progress bars.
+		return fn()
+	}
+
+	log.Level.SetLevel(zapcore.WarnLevel)
+	o.progress.start()
+	defer func() {
+		o.progress.stop()
+		log.Level.SetLevel(level)
+	}()
+	return fn()
+}
+
+// result is the document printed by every command with --output json. Fields
+// that do not apply to the command are omitted.
+type result struct {
+	Command      string             `json:"command"`
+	OK           bool               `json:"ok"`
+	ExitCode     int                `json:"exit_code"`
+	DurationMs   int64              `json:"duration_ms"`
+	Error        string             `json:"error,omitempty"`
+	Hint         string             `json:"hint,omitempty"`
+	Dependencies []dependencyResult `json:"dependencies,omitempty"`
+	Members      []memberResult     `json:"members,omitempty"`
+	Diagnostics  []diagnosticResult `json:"diagnostics,omitempty"`
+	Verify       *verifyResult      `json:"verify,omitempty"`
+	Migrations   []migrationResult  `json:"migrations,omitempty"`
+	Removed      *removedResult     `json:"removed,omitempty"`
+}
+
+type dependencyResult struct {
+	Name       string   `json:"name,omitempty"`
+	URL        string   `json:"url"`
+	Status     string   `json:"status"`
+	Commit     string   `json:"commit,omitempty"`
+	Tag        string   `json:"tag,omitempty"`
+	Files      []string `json:"files"`
+	DurationMs int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+type memberResult struct {
+	Dir          string             `json:"dir"`
+	OK           bool               `json:"ok"`
+	Error        string             `json:"error,omitempty"`
+	Dependencies []dependencyResult `json:"dependencies"`
+}
+
+type diagnosticResult struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+type verifyResult struct {
+	Added      []string `json:"added"`
+	Modified   []string `json:"modified"`
+	Missing    []string `json:"missing"`
+	Unverified []string `json:"unverified"`
+}
+
+type migrationResult struct {
+	Document string   `json:"document"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Changes  []string `json:"changes"`
+}
+
+type removedResult struct {
+	Name  string   `json:"name,omitempty"`
+	URL   string   `json:"url"`
+	Files []string `json:"files"`
+}
+
+func (r *result) setResult(result *control.Result) {
+	if result == nil {
+		return
+	}
+	r.Dependencies = dependencyResults(result)
+}
+
+func (r *result) setWorkspace(result *control.WorkspaceResult) {
+	if result == nil {
+		return
+	}
+
+	r.Members = []memberResult{}
+	for _, member := range result.Members {
+		res := memberResult{
+			Dir:          member.Dir,
+			OK:           member.Err == nil,
+			Dependencies: []dependencyResult{},
+		}
+		if member.Err != nil {
+			res.Error = member.Err.Error()
+		}
+		if member.Result != nil {
+			res.Dependencies = dependencyResults(member.Result)
+		}
+		r.Members = append(r.Members, res)
+	}
+}
+
+func dependencyResults(result *control.Result) []dependencyResult {
+	deps := []dependencyResult{}
+	for _, res := range result.Dependencies {
+		dep := dependencyResult{
+			Name:       res.Dependency.Name,
+			URL:        res.Dependency.URL,
+			Status:     string(res.Status),
+			Files:      nonNil(res.Replaced),
+			DurationMs: res.Duration.Milliseconds(),
+		}
+		if res.Err != nil {
+			dep.Error = res.Err.Error()
+		}
+		if res.Lock != nil {
+			dep.Commit = res.Lock.Commit
+			dep.Tag = res.Lock.Tag
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+func (r *result) setDiagnostics(diags vending.Diagnostics) {
+	r.Diagnostics = []diagnosticResult{}
+	for _, d := range diags {
+		r.Diagnostics = append(r.Diagnostics, diagnosticResult{
+			Severity: string(d.Severity),
+			Code:     d.Code,
+			Message:  d.Message,
+			Line:     d.Line,
+			Column:   d.Column,
+		})
+	}
+}
+
+func (r *result) setVerify(report *vending.VerifyReport) {
+	if report == nil {
+		return
+	}
+
+	r.Verify = &verifyResult{
+		Added:      nonNil(report.Added),
+		Modified:   nonNil(report.Modified),
+		Missing:    nonNil(report.Missing),
+		Unverified: nonNil(report.Unverified),
+	}
+}
+
+func (r *result) addMigration(document string, report *vending.MigrationReport) {
+	r.Migrations = append(r.Migrations, migrationResult{
+		Document: document,
+		From:     report.From,
+		To:       report.To,
+		Changes:  nonNil(report.Changes),
+	})
+}
+
+func (r *result) setRemoved(removed *control.RemoveResult) {
+	if removed == nil {
+		return
+	}
+
+	r.Removed = &removedResult{
+		Name:  removed.Dependency.Name,
+		URL:   removed.Dependency.URL,
+		Files: nonNil(removed.Files),
+	}
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
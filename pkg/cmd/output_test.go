@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutput_JSON(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	sut := &output{format: OutputJSON, stdout: stdout}
+	cmd := &cobra.Command{Use: "some-cmd"}
+
+	err := sut.run(func(cmd *cobra.Command, args []string, res *result) error {
+		res.setVerify(&vending.VerifyReport{Modified: []string{"some-file"}})
+		return fmt.Errorf("some error: %w", vending.ErrVerifyMismatch)
+	})(cmd, nil)
+	assert.ErrorIs(t, err, vending.ErrVerifyMismatch)
+
+	actual := map[string]any{}
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &actual))
+	assert.Equal(t, "some-cmd", actual["command"])
+	assert.Equal(t, false, actual["ok"])
+	assert.Equal(t, float64(ExitVerifyMismatch), actual["exit_code"])
+	assert.Equal(t, "some error: vendored files do not match the lockfile", actual["error"])
+	assert.Equal(t, []any{"some-file"}, actual["verify"].(map[string]any)["modified"])
+}
+
+func TestOutput_Text(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	sut := &output{format: OutputText, stdout: stdout}
+
+	err := sut.run(func(cmd *cobra.Command, args []string, res *result) error {
+		return nil
+	})(&cobra.Command{}, nil)
+
+	assert.NoError(t, err)
+	assert.Empty(t, stdout.String())
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/alevinval/vendor-go/pkg/event"
+	"github.com/fatih/color"
+)
+
+const progressBarWidth = 20
+
+// progress draws a line for every dependency, and updates it in place as
+// events arrive. It is an event.Sink that does nothing until started.
+type progress struct {
+	w io.Writer
+
+	mu      sync.Mutex
+	started bool
+	order   []string
+	lines   map[string]string
+	files   map[string]int
+	drawn   int
+}
+
+func newProgress(w io.Writer) *progress {
+	return &progress{w: w}
+}
+
+// isTerminal returns whether the file is attached to a terminal that can
+// redraw lines.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+}
+
+func (p *progress) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.started = true
+	p.order = []string{}
+	p.lines = map[string]string{}
+	p.files = map[string]int{}
+	p.drawn = 0
+}
+
+func (p *progress) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.started = false
+}
+
+func (p *progress) Emit(e event.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.started {
+		return
+	}
+
+	id := e.DependencyID()
+	if _, ok := p.lines[id]; !ok {
+		p.order = append(p.order, id)
+	}
+	p.lines[id] = p.describe(e)
+	p.redraw()
+}
+
+func (p *progress) describe(e event.Event) string {
+	switch e := e.(type) {
+	case event.DependencyStarted:
+		return color.YellowString(e.Revision)
+	case event.CloneStarted:
+		return "cloning..."
+	case event.FetchStarted:
+		return "fetching..."
+	case event.FetchProgress:
+		if e.Total == 0 {
+			return e.Stage
+		}
+		done := progressBarWidth * e.Current / e.Total
+		bar := strings.Repeat("#", done) + strings.Repeat("-", progressBarWidth-done)
+		return fmt.Sprintf("[%s] %3d%% %s", bar, 100*e.Current/e.Total, e.Stage)
+	case event.FileCopied:
+		p.files[e.Dependency]++
+		return fmt.Sprintf("copied %d files", p.files[e.Dependency])
+	case event.DependencyLocked:
+		if e.Tag != "" {
+			return fmt.Sprintf("🔒 %s (%s)", color.YellowString("%.8s", e.Commit), color.GreenString(e.Tag))
+		}
+		return fmt.Sprintf("🔒 %s", color.YellowString("%.8s", e.Commit))
+	case event.DependencyFailed:
+		return color.RedString("failed: %s", e.Err)
+	default:
+		return p.lines[e.DependencyID()]
+	}
+}
+
+func (p *progress) redraw() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.w, "\x1b[%dA", p.drawn)
+	}
+	for _, id := range p.order {
+		fmt.Fprintf(p.w, "\x1b[2K%s %s\n", color.CyanString(id), p.lines[id])
+	}
+	p.drawn = len(p.order)
+}
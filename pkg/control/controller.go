@@ -0,0 +1,630 @@
+// Package control drives the operations of the vending tool, it is what the
+// CLI runs, and can be used to vendor dependencies from Go programs without
+// running the CLI.
+package control
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/alevinval/vendor-go/internal/cache"
+	"github.com/alevinval/vendor-go/internal/installer"
+	"github.com/alevinval/vendor-go/internal/txn"
+	"github.com/alevinval/vendor-go/pkg/event"
+	"github.com/alevinval/vendor-go/pkg/log"
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/fatih/color"
+	"go.uber.org/zap"
+)
+
+// Option is used to apply customizations to the Controller.
+type Option = func(c *Controller)
+
+// WithPreset configures the Preset, the DefaultPreset is used otherwise.
+func WithPreset(preset vending.Preset) Option {
+	return func(c *Controller) {
+		c.preset = preset
+	}
+}
+
+// WithWorkDir configures the directory where the spec, the lockfile and the
+// vendor directory are, instead of the working directory of the process.
+func WithWorkDir(dir string) Option {
+	return func(c *Controller) {
+		c.workDir = dir
+	}
+}
+
+// WithSpecFile configures the spec file, instead of the one of the preset.
+// Relative paths are relative to the working directory.
+func WithSpecFile(filename string) Option {
+	return func(c *Controller) {
+		c.specFile = filename
+	}
+}
+
+// WithLockFile configures the lock file, instead of the one of the preset.
+// Relative paths are relative to the working directory.
+func WithLockFile(filename string) Option {
+	return func(c *Controller) {
+		c.lockFile = filename
+	}
+}
+
+// WithDiscovery makes the controller look for the spec in the parents of the
+// working directory too, the same way git looks for the .git directory. The
+// nearest spec is used, it has no effect with WithSpecFile.
+func WithDiscovery() Option {
+	return func(c *Controller) {
+		c.discover = true
+	}
+}
+
+// WithCacheDir configures where the repository cache is kept, instead of the
+// cache dir of the preset.
+func WithCacheDir(dir string) Option {
+	return func(c *Controller) {
+		c.cacheDir = dir
+	}
+}
+
+// WithLogger replaces the logger of the tool. The logger is shared by the
+// whole process, not only by this Controller, use zap.NewNop() to silence it.
+func WithLogger(logger *zap.Logger) Option {
+	return func(c *Controller) {
+		log.SetLogger(logger)
+	}
+}
+
+// WithEventSink configures the sink that receives the events emitted while
+// installing and updating dependencies.
+func WithEventSink(sink event.Sink) Option {
+	return func(c *Controller) {
+		c.sink = sink
+	}
+}
+
+// Controller knows how to execute the main business logic of the tool.
+type Controller struct {
+	preset   vending.Preset
+	workDir  string
+	specFile string
+	lockFile string
+	discover bool
+	root     vending.DirFS
+	cacheDir string
+	cache    *cache.Cache
+	sink     event.Sink
+}
+
+// New allocates a command controller based on the provided options.
+// The orchestrator contains the core logic of the operations that the vending
+// tool supports. This can be used to automate vending operations without having
+// to rely on running cobra commands.
+func New(opts ...Option) *Controller {
+	c := &Controller{
+		preset: &vending.DefaultPreset{},
+		sink:   event.Discard,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.preset = vending.WithFilenames(c.preset, c.specFile, c.lockFile)
+	c.root = vending.DirFS(c.workDir)
+	if c.discover && c.specFile == "" {
+		if dir, ok := vending.FindDir(c.workDir, c.preset.GetSpecFilename()); ok {
+			log.S().Debugf("using spec found in %s", dir)
+			c.root = vending.DirFS(dir)
+		}
+	}
+	if c.cacheDir == "" {
+		c.cacheDir = c.preset.GetCacheDir()
+	}
+	c.cache = cache.New(c.cacheDir)
+
+	return c
+}
+
+// Init initializes the vending tool for the working directory. This creates a
+// default spec in the filesystem. Specs in the parent directories are not
+// taken into account.
+func (c *Controller) Init() error {
+	root := vending.DirFS(c.workDir)
+	_, err := root.ReadFile(c.preset.GetSpecFilename())
+	if err == nil {
+		return fmt.Errorf("%q already exists? %w", c.preset.GetSpecFilename(), err)
+	}
+
+	spec := vending.NewSpec(c.preset).WithFS(root)
+
+	err = spec.Save()
+	if err != nil {
+		return fmt.Errorf("cannot save spec: %w", err)
+	}
+
+	log.S().Infof("%s has been created ✅", c.preset.GetSpecFilename())
+	return nil
+}
+
+// ConcurrencyOptions limit how many dependencies are vendored at the same
+// time. Zero values fall back to the ones of the preset.
+type ConcurrencyOptions struct {
+	// Jobs is the maximum number of dependencies vendored at the same time.
+	Jobs int
+
+	// JobsPerHost is the maximum number of dependencies fetched from the same
+	// host at the same time.
+	JobsPerHost int
+}
+
+// InstallOptions customize the behavior of Install.
+type InstallOptions struct {
+	ConcurrencyOptions
+
+	// Frozen refuses to install when the lockfile is not in sync with the
+	// spec, and never writes the spec nor the lockfile.
+	Frozen bool
+
+	// KeepGoing vendors every dependency that succeeded even when others
+	// fail. An error is still returned when any of them failed.
+	KeepGoing bool
+
+	// LockTimeout is how long to wait for other instances of the tool to
+	// release the cache, zero waits as long as needed.
+	LockTimeout time.Duration
+}
+
+// UpdateOptions customize the behavior of Update.
+type UpdateOptions struct {
+	ConcurrencyOptions
+
+	// Dependencies, by name or URL, restricts the update to them. The other
+	// dependencies stay at their locked commit, and their vendored files are
+	// not rewritten. Empty updates every dependency.
+	Dependencies []string
+
+	// FailOnExpiredPins refuses to update when any pin is past its Until
+	// date, instead of only warning about it.
+	FailOnExpiredPins bool
+
+	// KeepGoing vendors every dependency that succeeded even when others
+	// fail. An error is still returned when any of them failed.
+	KeepGoing bool
+
+	// LockTimeout is how long to wait for other instances of the tool to
+	// release the cache, zero waits as long as needed.
+	LockTimeout time.Duration
+}
+
+// Install vendors the dependencies at the version specified by the lockfile.
+// When no lockfile is present, it locks the dependencies at the latest
+// reference of the branch that the spec defines for each dependency. When the
+// context is done, in-flight work stops and nothing is written. The result
+// holds the outcome of every dependency, it is nil when nothing was vendored.
+func (c *Controller) Install(ctx context.Context, opts InstallOptions) (*Result, error) {
+	start := time.Now()
+	report, err := c.install(ctx, opts)
+	return newResult(report, start), err
+}
+
+func (c *Controller) install(ctx context.Context, opts InstallOptions) (*installer.Report, error) {
+	if _, err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	lock, err := c.cache.Lock(ctx, opts.LockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot lock cache: %w", err)
+	}
+	defer lock.Release()
+
+	log.S().Infof("repository cache located at %s",
+		color.MagentaString(c.cacheDir),
+	)
+
+	return c.installLocked(ctx, opts, nil)
+}
+
+// installLocked installs the dependencies, once the cache is locked. Installs
+// that share fetched fetch every repository once.
+func (c *Controller) installLocked(ctx context.Context, opts InstallOptions, fetched *installer.FetchSet) (*installer.Report, error) {
+	spec := c.newSpec()
+	if err := spec.Load(); err != nil {
+		return nil, fmt.Errorf("cannot load spec: %w", err)
+	}
+
+	specLock := c.newSpecLock()
+	if err := specLock.Load(); err != nil {
+		return nil, fmt.Errorf("cannot load speclock: %w", err)
+	}
+
+	if opts.Frozen {
+		if diff := specLock.Diff(spec); len(diff) > 0 {
+			for _, line := range diff {
+				log.S().Errorf("  %s", line)
+			}
+			return nil, fmt.Errorf("%w: %s does not match %s",
+				vending.ErrLockOutOfDate, c.preset.GetSpecLockFilename(), c.preset.GetSpecFilename())
+		}
+	}
+
+	ins := c.newInstaller(spec, specLock, opts.ConcurrencyOptions).
+		WithKeepGoing(opts.KeepGoing).
+		WithFetchSet(fetched)
+	report, err := ins.Install(ctx)
+	logReport(ctx, report)
+	// Nothing is staged once interrupted, not even with KeepGoing.
+	if err != nil && (report == nil || !opts.KeepGoing || ctx.Err() != nil) {
+		return report, fmt.Errorf("cannot install: %w", err)
+	}
+
+	if err := c.commit(spec, specLock, !opts.Frozen, ins.Commit); err != nil {
+		ins.Discard()
+		return report, err
+	}
+
+	if err != nil {
+		return report, fmt.Errorf("cannot install: %w", err)
+	}
+
+	log.S().Infof("install success ✅")
+	return report, nil
+}
+
+// Update vendors the dependencies at the latest reference from the specified
+// branch, this updates the lockfile with the locked references for each
+// dependency. When the context is done, in-flight work stops and nothing is
+// written. The result holds the outcome of every dependency, it is nil when
+// nothing was vendored.
+func (c *Controller) Update(ctx context.Context, opts UpdateOptions) (*Result, error) {
+	start := time.Now()
+	report, err := c.update(ctx, opts)
+	return newResult(report, start), err
+}
+
+func (c *Controller) update(ctx context.Context, opts UpdateOptions) (*installer.Report, error) {
+	if _, err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	lock, err := c.cache.Lock(ctx, opts.LockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot lock cache: %w", err)
+	}
+	defer lock.Release()
+
+	spec := c.newSpec()
+	if err := spec.Load(); err != nil {
+		return nil, fmt.Errorf("cannot load spec: %w", err)
+	}
+
+	if err := checkPins(spec, time.Now(), opts.FailOnExpiredPins); err != nil {
+		return nil, err
+	}
+
+	specLock := c.newSpecLock()
+	if err := specLock.Load(); err != nil {
+		return nil, fmt.Errorf("cannot load speclock: %w", err)
+	}
+
+	log.S().Infof("repository cache located at %s",
+		color.MagentaString(c.cacheDir),
+	)
+
+	ins := c.newInstaller(spec, specLock, opts.ConcurrencyOptions).WithKeepGoing(opts.KeepGoing)
+	if len(opts.Dependencies) > 0 {
+		selection, err := c.selectDependencies(spec, specLock, opts.Dependencies)
+		if err != nil {
+			return nil, err
+		}
+		ins.WithSelection(selection)
+	}
+
+	report, err := ins.Update(ctx)
+	logReport(ctx, report)
+	// Nothing is staged once interrupted, not even with KeepGoing.
+	if err != nil && (report == nil || !opts.KeepGoing || ctx.Err() != nil) {
+		return report, fmt.Errorf("cannot update: %w", err)
+	}
+
+	if err := c.commit(spec, specLock, true, ins.Commit); err != nil {
+		ins.Discard()
+		return report, err
+	}
+
+	if err != nil {
+		return report, fmt.Errorf("cannot update: %w", err)
+	}
+
+	log.S().Infof("update success ✅")
+	return report, nil
+}
+
+// selectDependencies finds the dependencies of the spec with the given names
+// or URLs.
+func (c *Controller) selectDependencies(spec *vending.Spec, specLock *vending.SpecLock, ids []string) ([]*vending.Dependency, error) {
+	selection := []*vending.Dependency{}
+	for _, id := range ids {
+		dep, ok := spec.FindDependency(id)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not in %s", vending.ErrDependencyNotFound, id, c.preset.GetSpecFilename())
+		}
+		selection = append(selection, dep)
+	}
+
+	for _, dep := range spec.Deps {
+		if _, ok := specLock.FindByID(dep.ID()); !ok && !slices.Contains(selection, dep) {
+			log.S().Warnf("%s is not locked, run install to vendor it", color.CyanString(dep.ID()))
+		}
+	}
+	return selection, nil
+}
+
+func (c *Controller) newInstaller(spec *vending.Spec, specLock *vending.SpecLock, opts ConcurrencyOptions) *installer.Installer {
+	jobs, jobsPerHost := vending.Concurrency(c.preset)
+	if opts.Jobs > 0 {
+		jobs = opts.Jobs
+	}
+	if opts.JobsPerHost > 0 {
+		jobsPerHost = opts.JobsPerHost
+	}
+	return installer.New(c.cache, spec, specLock).
+		WithVendorDir(c.vendorDir(spec)).
+		WithJobs(jobs, jobsPerHost).
+		WithSink(c.sink)
+}
+
+// vendorDir returns the path of the vendor directory of the spec, which is
+// relative to the working directory of the controller.
+func (c *Controller) vendorDir(spec *vending.Spec) string {
+	return c.root.Path(spec.VendorDir)
+}
+
+func (c *Controller) newSpec() *vending.Spec {
+	return vending.NewSpec(c.preset).WithFS(c.root)
+}
+
+func (c *Controller) newSpecLock() *vending.SpecLock {
+	return vending.NewSpecLock(c.preset).WithFS(c.root)
+}
+
+func logReport(ctx context.Context, report *installer.Report) {
+	if report == nil {
+		return
+	}
+	if ctx.Err() != nil {
+		log.S().Warnf("%s, nothing has been written", color.RedString("interrupted"))
+		return
+	}
+
+	for _, result := range report.Results {
+		id := color.CyanString(result.Dependency.ID())
+		switch result.Status {
+		case installer.StatusFailed:
+			log.S().Errorf("  %s %s: %s", color.RedString("failed"), id, result.Err)
+		case installer.StatusSkipped:
+			log.S().Infof("  %s %s", color.YellowString("skipped"), id)
+		default:
+			log.S().Infof("  %s %s", color.GreenString("succeeded"), id)
+		}
+	}
+}
+
+// commit applies the filesystem changes of apply and, when save is set,
+// writes the spec and the lockfile, unless specLock is nil. Either all of them
+// are written, or everything is rolled back to how it was.
+func (c *Controller) commit(spec *vending.Spec, specLock *vending.SpecLock, save bool, apply func(*txn.Transaction) error) (err error) {
+	tx := txn.New()
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				log.S().Errorf("%s", rollbackErr)
+			}
+		}
+	}()
+
+	files := map[string]func() ([]byte, error){}
+	if save {
+		files[c.root.Path(c.preset.GetSpecFilename())] = spec.Marshal
+		if specLock != nil {
+			files[c.root.Path(c.preset.GetSpecLockFilename())] = specLock.Marshal
+		}
+	}
+
+	// Marshal everything before touching the filesystem, the most likely
+	// failures happen before anything has to be rolled back.
+	contents := map[string][]byte{}
+	for filename, marshal := range files {
+		data, err := marshal()
+		if err != nil {
+			return fmt.Errorf("cannot marshal %s: %w", filename, err)
+		}
+		contents[filename] = data
+	}
+
+	if err := apply(tx); err != nil {
+		return err
+	}
+
+	for _, filename := range []string{c.root.Path(c.preset.GetSpecFilename()), c.root.Path(c.preset.GetSpecLockFilename())} {
+		data, ok := contents[filename]
+		if !ok {
+			continue
+		}
+		if err := tx.WriteFile(filename, data); err != nil {
+			return fmt.Errorf("cannot save %s: %w", filename, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Validate checks the spec for mistakes, reporting every problem found. It
+// returns an error when any of the problems is an error.
+func (c *Controller) Validate() (vending.Diagnostics, error) {
+	diags, err := c.validate()
+	if err != nil {
+		return diags, err
+	}
+
+	log.S().Infof("%s is valid ✅", c.preset.GetSpecFilename())
+	return diags, nil
+}
+
+func (c *Controller) validate() (vending.Diagnostics, error) {
+	filename := c.preset.GetSpecFilename()
+	diags, err := vending.ValidateSpec(c.root, c.preset)
+	if err != nil {
+		return nil, fmt.Errorf("cannot validate spec: %w", err)
+	}
+
+	for _, d := range diags {
+		msg := fmt.Sprintf("%s:%d:%d: %s [%s]", filename, d.Line, d.Column, d.Message, d.Code)
+		if d.Severity == vending.SeverityError {
+			log.S().Errorf("%s %s", color.RedString("error"), msg)
+		} else {
+			log.S().Warnf("%s %s", color.YellowString("warning"), msg)
+		}
+	}
+
+	if diags.HasErrors() {
+		return diags, fmt.Errorf("%w: %d error(s) found in %s", vending.ErrSpecInvalid, diags.Count(vending.SeverityError), filename)
+	}
+	return diags, nil
+}
+
+// Add adds a new dependency into the spec file.
+func (c *Controller) Add(dep *vending.Dependency) error {
+	if err := dep.CheckPaths(); err != nil {
+		return fmt.Errorf("invalid dependency: %w", err)
+	}
+
+	spec := c.newSpec()
+	if err := spec.Load(); err != nil {
+		return fmt.Errorf("cannot load spec: %w", err)
+	}
+
+	spec.AddDependency(dep)
+
+	if err := spec.Save(); err != nil {
+		return fmt.Errorf("cannot save spec: %w", err)
+	}
+
+	log.S().Infof("added dependency %s@%s ✅",
+		color.CyanString(dep.ID()),
+		color.YellowString(dep.Revision()),
+	)
+	return nil
+}
+
+// Verify checks that the files in the vendor directory match the digests that
+// were recorded in the lockfile. It does not access the network.
+func (c *Controller) Verify() (*vending.VerifyReport, error) {
+	spec := c.newSpec()
+	if err := spec.Load(); err != nil {
+		return nil, fmt.Errorf("cannot load spec: %w", err)
+	}
+
+	specLock := c.newSpecLock()
+	if err := specLock.Load(); err != nil {
+		return nil, fmt.Errorf("cannot load speclock: %w", err)
+	}
+
+	vendorDir := c.vendorDir(spec)
+	report, err := specLock.Verify(vendorDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot verify: %w", err)
+	}
+
+	for _, id := range report.Unverified {
+		log.S().Warnf("%s %s has no file digests, run install to record them", color.YellowString("unverified"), id)
+	}
+	for _, path := range report.Added {
+		log.S().Errorf("%s %s", color.GreenString("added"), path)
+	}
+	for _, path := range report.Modified {
+		log.S().Errorf("%s %s", color.YellowString("modified"), path)
+	}
+	for _, path := range report.Missing {
+		log.S().Errorf("%s %s", color.RedString("missing"), path)
+	}
+
+	if len(report.Added) > 0 {
+		return report, fmt.Errorf("%w: %s does not match %s, it has %w", vending.ErrVerifyMismatch, vendorDir, c.preset.GetSpecLockFilename(), vending.ErrUnvendoredFiles)
+	}
+	if !report.OK() {
+		return report, fmt.Errorf("%w: %s does not match %s", vending.ErrVerifyMismatch, vendorDir, c.preset.GetSpecLockFilename())
+	}
+
+	log.S().Infof("verify success ✅")
+	return report, nil
+}
+
+// MigrateResult holds what changed in the spec and in the lockfile when
+// migrating them.
+type MigrateResult struct {
+	Spec *vending.MigrationReport
+	Lock *vending.MigrationReport
+}
+
+// Migrate rewrites the spec and the lockfile produced by an older version of
+// the tool, to the schema of the current version.
+func (c *Controller) Migrate() (*MigrateResult, error) {
+	spec := c.newSpec()
+	specReport, err := spec.LoadAndMigrate()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load spec: %w", err)
+	}
+
+	specLock := c.newSpecLock()
+	lockReport, err := specLock.LoadAndMigrate()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load speclock: %w", err)
+	}
+
+	logMigration(c.preset.GetSpecFilename(), specReport)
+	logMigration(c.preset.GetSpecLockFilename(), lockReport)
+
+	// A missing lock file is not created, there is nothing to migrate.
+	if _, err := c.root.ReadFile(c.preset.GetSpecLockFilename()); err != nil {
+		specLock = nil
+	}
+
+	if err := c.commit(spec, specLock, true, func(*txn.Transaction) error { return nil }); err != nil {
+		return nil, fmt.Errorf("cannot migrate: %w", err)
+	}
+
+	log.S().Infof("migrate success ✅")
+	return &MigrateResult{Spec: specReport, Lock: lockReport}, nil
+}
+
+func logMigration(filename string, report *vending.MigrationReport) {
+	if report.IsEmpty() {
+		log.S().Infof("%s is up to date", filename)
+		return
+	}
+
+	log.S().Infof("migrated %s from %s to %s",
+		filename,
+		color.YellowString(report.From),
+		color.GreenString(report.To),
+	)
+	for _, change := range report.Changes {
+		log.S().Infof("  - %s", change)
+	}
+}
+
+// CleanCache performs a reset of the repository cache, once cleaned, the
+// repositories of the dependencies will have to be cloned again.
+func (c *Controller) CleanCache() error {
+	if err := c.cache.Reset(); err != nil {
+		return fmt.Errorf("cannot clean cache: %w", err)
+	}
+
+	log.S().Infof("cache has been cleaned ✅")
+	return nil
+}
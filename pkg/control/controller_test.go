@@ -0,0 +1,120 @@
+package control
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alevinval/vendor-go/pkg/vending"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testPreset = &vending.DefaultPreset{}
+
+// upstream is a git repository that dependencies are vendored from.
+type upstream struct {
+	t    *testing.T
+	dir  string
+	repo *gogit.Repository
+}
+
+func newUpstream(t *testing.T, files map[string]string) *upstream {
+	dir := t.TempDir()
+	repo, err := gogit.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	u := &upstream{t, dir, repo}
+	u.commit(files)
+	return u
+}
+
+// commit writes the files and commits them, it returns the commit.
+func (u *upstream) commit(files map[string]string) string {
+	worktree, err := u.repo.Worktree()
+	require.NoError(u.t, err)
+
+	for path, data := range files {
+		require.NoError(u.t, os.WriteFile(filepath.Join(u.dir, path), []byte(data), 0o644))
+		_, err := worktree.Add(path)
+		require.NoError(u.t, err)
+	}
+
+	hash, err := worktree.Commit("commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@test", When: time.Now()},
+	})
+	require.NoError(u.t, err)
+	return hash.String()
+}
+
+// testProject is a directory with a spec, a lockfile and a vendor directory,
+// as the default preset names them.
+type testProject struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestProject(t *testing.T) *testProject {
+	return &testProject{t: t, dir: t.TempDir()}
+}
+
+// member returns the project in dir, relative to p.
+func (p *testProject) member(dir string) *testProject {
+	require.NoError(p.t, os.MkdirAll(p.path(dir), os.ModePerm))
+	return &testProject{t: p.t, dir: p.path(dir)}
+}
+
+func (p *testProject) controller(opts ...Option) *Controller {
+	opts = append([]Option{
+		WithWorkDir(p.dir),
+		WithCacheDir(filepath.Join(p.dir, ".cache")),
+		WithLogger(zap.NewNop()),
+	}, opts...)
+	return New(opts...)
+}
+
+func (p *testProject) saveSpec(deps ...*vending.Dependency) {
+	spec := vending.NewSpec(testPreset).WithFS(vending.DirFS(p.dir))
+	for _, dep := range deps {
+		spec.AddDependency(dep)
+	}
+	require.NoError(p.t, spec.Save())
+}
+
+func (p *testProject) saveLock(locks ...*vending.DependencyLock) {
+	specLock := vending.NewSpecLock(testPreset).WithFS(vending.DirFS(p.dir))
+	for _, lock := range locks {
+		specLock.AddDependencyLock(lock)
+	}
+	require.NoError(p.t, specLock.Save())
+}
+
+func (p *testProject) loadSpec() *vending.Spec {
+	spec := vending.NewSpec(testPreset).WithFS(vending.DirFS(p.dir))
+	require.NoError(p.t, spec.Load())
+	return spec
+}
+
+func (p *testProject) loadLock() *vending.SpecLock {
+	specLock := vending.NewSpecLock(testPreset).WithFS(vending.DirFS(p.dir))
+	require.NoError(p.t, specLock.Load())
+	return specLock
+}
+
+func (p *testProject) path(path string) string {
+	return filepath.Join(p.dir, filepath.FromSlash(path))
+}
+
+func (p *testProject) write(path, data string) {
+	require.NoError(p.t, os.MkdirAll(filepath.Dir(p.path(path)), os.ModePerm))
+	require.NoError(p.t, os.WriteFile(p.path(path), []byte(data), 0o644))
+}
+
+func (p *testProject) read(path string) string {
+	data, err := os.ReadFile(p.path(path))
+	require.NoError(p.t, err)
+	return string(data)
+}
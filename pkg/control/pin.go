@@ -0,0 +1,103 @@
+package control
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alevinval/vendor-go/pkg/log"
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/fatih/color"
+)
+
+// PinOptions hold the metadata recorded when pinning a dependency.
+type PinOptions struct {
+	// Reason describes why the dependency is pinned.
+	Reason string
+
+	// Until is the date, formatted as YYYY-MM-DD, after which the pin has to
+	// be revisited. Update warns about expired pins.
+	Until string
+}
+
+// Pin pins a dependency, found by its name or its URL, so Update keeps it at
+// its locked commit. Pinning a pinned dependency replaces its metadata.
+func (c *Controller) Pin(id string, opts PinOptions) error {
+	pin := &vending.Pin{Reason: opts.Reason, Until: opts.Until}
+	if _, _, err := pin.UntilDate(); err != nil {
+		return fmt.Errorf("invalid pin: %w", err)
+	}
+	if *pin == (vending.Pin{}) {
+		pin = nil
+	}
+
+	dep, err := c.updateDependency(id, func(dep *vending.Dependency) {
+		dep.Pinned = true
+		dep.Pin = pin
+	})
+	if err != nil {
+		return err
+	}
+
+	log.S().Infof("pinned %s ✅", color.CyanString(dep.ID()))
+	return nil
+}
+
+// Unpin unpins a dependency, found by its name or its URL, and removes its pin
+// metadata.
+func (c *Controller) Unpin(id string) error {
+	dep, err := c.updateDependency(id, func(dep *vending.Dependency) {
+		dep.Pinned = false
+		dep.Pin = nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.S().Infof("unpinned %s ✅", color.CyanString(dep.ID()))
+	return nil
+}
+
+func (c *Controller) updateDependency(id string, fn func(*vending.Dependency)) (*vending.Dependency, error) {
+	spec := c.newSpec()
+	if err := spec.Load(); err != nil {
+		return nil, fmt.Errorf("cannot load spec: %w", err)
+	}
+
+	dep, ok := spec.FindDependency(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not in %s", vending.ErrDependencyNotFound, id, c.preset.GetSpecFilename())
+	}
+	fn(dep)
+
+	if err := spec.Save(); err != nil {
+		return nil, fmt.Errorf("cannot save spec: %w", err)
+	}
+	return dep, nil
+}
+
+// checkPins warns about the pins that expired before now, and fails when
+// strict is set.
+func checkPins(spec *vending.Spec, now time.Time, strict bool) error {
+	expired := 0
+	for _, dep := range spec.Deps {
+		if !dep.PinExpired(now) {
+			continue
+		}
+		expired++
+
+		msg := fmt.Sprintf("pin of %s expired on %s", color.CyanString(dep.ID()), color.YellowString(dep.Pin.Until))
+		if dep.Pin.Reason != "" {
+			msg += fmt.Sprintf(" (%s)", dep.Pin.Reason)
+		}
+		if strict {
+			log.S().Errorf("%s", msg)
+		} else {
+			log.S().Warnf("%s", msg)
+		}
+	}
+
+	if strict && expired > 0 {
+		return fmt.Errorf("%w: %d pin(s) expired", vending.ErrPinExpired, expired)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package control
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const pinSpec = `# The dependencies of the project.
+version: v0.5.1
+preset: default
+vendor_dir: vendor/
+deps:
+  # Pinned while the api is in flux.
+  - url: some-url
+    name: some-dep
+    branch: master
+`
+
+func newPinnedProject(t *testing.T, until string) *testProject {
+	sut := newTestProject(t)
+	sut.write(testPreset.GetSpecFilename(), pinSpec)
+	require.NoError(t, sut.controller().Pin("some-dep", PinOptions{Reason: "some reason", Until: until}))
+	return sut
+}
+
+func TestController_Pin_ThenUnpin_RestoresSpec(t *testing.T) {
+	sut := newPinnedProject(t, "2000-01-01")
+
+	dep := sut.loadSpec().Deps[0]
+	assert.True(t, dep.Pinned)
+	assert.Equal(t, &vending.Pin{Reason: "some reason", Until: "2000-01-01"}, dep.Pin)
+	assert.Contains(t, sut.read(testPreset.GetSpecFilename()), "# Pinned while the api is in flux.")
+
+	require.NoError(t, sut.controller().Unpin("some-url"))
+
+	assert.Equal(t, pinSpec, sut.read(testPreset.GetSpecFilename()))
+}
+
+func TestController_Pin_InvalidUntil(t *testing.T) {
+	sut := newTestProject(t)
+	sut.write(testPreset.GetSpecFilename(), pinSpec)
+
+	err := sut.controller().Pin("some-dep", PinOptions{Until: "tomorrow"})
+
+	assert.Error(t, err)
+	assert.Equal(t, pinSpec, sut.read(testPreset.GetSpecFilename()))
+}
+
+func TestCheckPins_WhenExpired_Warns(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	sut := newPinnedProject(t, "2000-01-01")
+	sut.controller(WithLogger(zap.New(core)))
+
+	err := checkPins(sut.loadSpec(), time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), false)
+
+	assert.NoError(t, err)
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, zapcore.WarnLevel, logs.All()[0].Level)
+	assert.Contains(t, logs.All()[0].Message, "expired on")
+	assert.Contains(t, logs.All()[0].Message, "(some reason)")
+}
+
+func TestCheckPins_OnUntilDate_DoesNotWarn(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	sut := newPinnedProject(t, "2000-01-01")
+	sut.controller(WithLogger(zap.New(core)))
+
+	err := checkPins(sut.loadSpec(), time.Date(2000, 1, 1, 23, 0, 0, 0, time.UTC), true)
+
+	assert.NoError(t, err)
+	assert.Zero(t, logs.Len())
+}
+
+func TestController_Update_WhenFailOnExpiredPins_Fails(t *testing.T) {
+	sut := newPinnedProject(t, "2000-01-01")
+
+	_, err := sut.controller().Update(context.Background(), UpdateOptions{FailOnExpiredPins: true})
+
+	assert.ErrorIs(t, err, vending.ErrPinExpired)
+	assert.NoFileExists(t, sut.path(testPreset.GetSpecLockFilename()))
+}
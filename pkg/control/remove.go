@@ -0,0 +1,96 @@
+package control
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/alevinval/vendor-go/internal/txn"
+	"github.com/alevinval/vendor-go/pkg/log"
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/fatih/color"
+)
+
+// RemoveResult holds the dependency that was removed, and the files deleted
+// from the vendor directory, relative to it.
+type RemoveResult struct {
+	Dependency *vending.Dependency
+	Files      []string
+}
+
+// Remove removes a dependency, found by its name or its URL, from the spec and
+// the lockfile, and deletes the files that the lockfile recorded for it. Files
+// that other dependencies vendored too are kept. Either everything is removed,
+// or nothing is.
+func (c *Controller) Remove(id string) (*RemoveResult, error) {
+	spec := c.newSpec()
+	if err := spec.Load(); err != nil {
+		return nil, fmt.Errorf("cannot load spec: %w", err)
+	}
+
+	specLock := c.newSpecLock()
+	if err := specLock.Load(); err != nil {
+		return nil, fmt.Errorf("cannot load speclock: %w", err)
+	}
+
+	dep, ok := spec.RemoveDependency(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not in %s", vending.ErrDependencyNotFound, id, c.preset.GetSpecFilename())
+	}
+
+	files := []string{}
+	if depLock, ok := specLock.RemoveByID(dep.ID()); ok {
+		files = ownedFiles(depLock, specLock)
+		if len(depLock.Files) == 0 {
+			log.S().Warnf("%s has no files recorded in %s, run install to remove its files",
+				color.CyanString(dep.ID()), c.preset.GetSpecLockFilename())
+		}
+	}
+
+	vendorDir := c.vendorDir(spec)
+	err := c.commit(spec, specLock, true, func(tx *txn.Transaction) error {
+		for _, path := range files {
+			if err := tx.RemoveFile(filepath.Join(vendorDir, filepath.FromSlash(path))); err != nil {
+				return fmt.Errorf("cannot remove %s: %w", path, err)
+			}
+		}
+		tx.OnCommit(func() {
+			txn.RemoveEmptyDirs(vendorDir, files)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range files {
+		log.S().Debugf("  [removed] %s", path)
+	}
+	log.S().Infof("removed dependency %s and %d file(s) ✅", color.CyanString(dep.ID()), len(files))
+	return &RemoveResult{Dependency: dep, Files: files}, nil
+}
+
+// ownedFiles returns the files of the lock that no other dependency of the
+// lockfile has vendored, sorted.
+func ownedFiles(depLock *vending.DependencyLock, specLock *vending.SpecLock) []string {
+	shared := map[string]bool{}
+	for _, other := range specLock.Deps {
+		for path := range other.Files {
+			shared[path] = true
+		}
+	}
+
+	files := []string{}
+	for _, path := range slices.Sorted(maps.Keys(depLock.Files)) {
+		switch {
+		case !filepath.IsLocal(filepath.FromSlash(path)):
+			log.S().Warnf("skipping %s, it is outside of the vendor directory", path)
+		case shared[path]:
+			log.S().Debugf("  [keep] %s, vendored by another dependency", path)
+		default:
+			files = append(files, path)
+		}
+	}
+	return files
+}
@@ -0,0 +1,72 @@
+package control
+
+import (
+	"testing"
+
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRemoveProject vendors two dependencies, that share a file.
+func newRemoveProject(t *testing.T) *testProject {
+	sut := newTestProject(t)
+
+	one, two := vending.NewDependency("one-url", "master"), vending.NewDependency("two-url", "master")
+	one.Name, two.Name = "one", "two"
+	sut.saveSpec(one, two)
+
+	oneLock, twoLock := vending.NewDependencyLock("one-url", "one-commit"), vending.NewDependencyLock("two-url", "two-commit")
+	oneLock.Name, twoLock.Name = "one", "two"
+	oneLock.SetFiles(map[string]string{"one/a.proto": "sha256:a", "shared.proto": "sha256:shared"})
+	twoLock.SetFiles(map[string]string{"two/b.proto": "sha256:b", "shared.proto": "sha256:shared"})
+	sut.saveLock(oneLock, twoLock)
+
+	for _, path := range []string{"vendor/one/a.proto", "vendor/two/b.proto", "vendor/shared.proto"} {
+		sut.write(path, path)
+	}
+	return sut
+}
+
+func TestController_Remove_RemovesFilesOfTheDependency(t *testing.T) {
+	sut := newRemoveProject(t)
+
+	result, err := sut.controller().Remove("one")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one/a.proto"}, result.Files)
+	assert.NoFileExists(t, sut.path("vendor/one/a.proto"))
+	assert.NoDirExists(t, sut.path("vendor/one"))
+	assert.FileExists(t, sut.path("vendor/two/b.proto"))
+}
+
+func TestController_Remove_KeepsSharedFiles(t *testing.T) {
+	sut := newRemoveProject(t)
+
+	_, err := sut.controller().Remove("one")
+
+	require.NoError(t, err)
+	assert.Equal(t, "vendor/shared.proto", sut.read("vendor/shared.proto"))
+}
+
+func TestController_Remove_RemovesSpecAndLockEntries(t *testing.T) {
+	sut := newRemoveProject(t)
+
+	_, err := sut.controller().Remove("one-url")
+	require.NoError(t, err)
+
+	spec, specLock := sut.loadSpec(), sut.loadLock()
+	assert.Len(t, spec.Deps, 1)
+	assert.Equal(t, "two", spec.Deps[0].Name)
+	assert.Len(t, specLock.Deps, 1)
+	assert.Equal(t, "two", specLock.Deps[0].Name)
+}
+
+func TestController_Remove_WhenNotFound(t *testing.T) {
+	sut := newRemoveProject(t)
+
+	_, err := sut.controller().Remove("other")
+
+	assert.ErrorIs(t, err, vending.ErrDependencyNotFound)
+	assert.FileExists(t, sut.path("vendor/one/a.proto"))
+}
@@ -0,0 +1,70 @@
+package control
+
+import (
+	"time"
+
+	"github.com/alevinval/vendor-go/internal/installer"
+	"github.com/alevinval/vendor-go/pkg/vending"
+)
+
+// Status is the outcome of vendoring a dependency.
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusSkipped   Status = "skipped"
+	StatusFailed    Status = "failed"
+)
+
+// DependencyResult is the outcome of vendoring a single dependency. Lock is
+// the lock of the dependency after the operation, for failed dependencies it
+// is the lock they had before, if any. Err holds the cause of the failure.
+// Replaced lists the files that were written into the vendor directory.
+type DependencyResult struct {
+	Dependency *vending.Dependency
+	Status     Status
+	Lock       *vending.DependencyLock
+	Replaced   []string
+	Err        error
+	Duration   time.Duration
+}
+
+// Result is returned by Install and Update, it holds the result of every
+// dependency, in spec order.
+type Result struct {
+	Dependencies []*DependencyResult
+	Duration     time.Duration
+}
+
+// Failed returns the results of the dependencies that failed.
+func (r *Result) Failed() []*DependencyResult {
+	failed := []*DependencyResult{}
+	for _, dep := range r.Dependencies {
+		if dep.Status == StatusFailed {
+			failed = append(failed, dep)
+		}
+	}
+	return failed
+}
+
+func newResult(report *installer.Report, start time.Time) *Result {
+	if report == nil {
+		return nil
+	}
+
+	result := &Result{
+		Dependencies: make([]*DependencyResult, 0, len(report.Results)),
+		Duration:     time.Since(start),
+	}
+	for _, res := range report.Results {
+		result.Dependencies = append(result.Dependencies, &DependencyResult{
+			Dependency: res.Dependency,
+			Status:     Status(res.Status),
+			Lock:       res.Lock,
+			Replaced:   res.Replaced,
+			Err:        res.Err,
+			Duration:   res.Duration,
+		})
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/alevinval/vendor-go/internal/installer"
+	"github.com/alevinval/vendor-go/pkg/log"
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/fatih/color"
+)
+
+// WorkspaceResult is returned by InstallWorkspace, it holds the result of
+// every member that was installed, in workspace order.
+type WorkspaceResult struct {
+	Dir      string
+	Members  []*MemberResult
+	Duration time.Duration
+}
+
+// MemberResult is the outcome of installing a member of the workspace. Dir is
+// relative to the workspace, Result is nil when nothing was vendored.
+type MemberResult struct {
+	Dir    string
+	Result *Result
+	Err    error
+}
+
+// Failed returns the results of the members that failed.
+func (r *WorkspaceResult) Failed() []*MemberResult {
+	failed := []*MemberResult{}
+	for _, member := range r.Members {
+		if member.Err != nil {
+			failed = append(failed, member)
+		}
+	}
+	return failed
+}
+
+// InstallWorkspace installs every member of the workspace, which is declared
+// by the nearest workspace file, in the working directory or in its parents.
+// The cache is locked once for all of them, and repositories that several
+// members depend on are fetched once. Members are installed one after the
+// other, each of them atomically, it stops at the first member that fails
+// unless KeepGoing is set.
+func (c *Controller) InstallWorkspace(ctx context.Context, opts InstallOptions) (*WorkspaceResult, error) {
+	start := time.Now()
+	if c.specFile != "" || c.lockFile != "" {
+		return nil, fmt.Errorf("cannot install a workspace with a custom spec or lock file, members use the ones of the preset")
+	}
+
+	dir, ok := vending.FindDir(c.workDir, vending.WorkspaceFilename)
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s in the working directory, nor in its parents", vending.ErrWorkspaceNotFound, vending.WorkspaceFilename)
+	}
+
+	ws, err := vending.LoadWorkspace(vending.DirFS(dir))
+	if err != nil {
+		return nil, fmt.Errorf("cannot load workspace: %w", err)
+	}
+
+	// Every member is validated before anything is installed.
+	members := make([]*Controller, len(ws.Members))
+	for i, memberDir := range ws.Members {
+		members[i] = c.member(filepath.Join(dir, memberDir))
+		if _, err := members[i].validate(); err != nil {
+			return nil, fmt.Errorf("cannot validate %s: %w", memberDir, err)
+		}
+	}
+
+	lock, err := c.cache.Lock(ctx, opts.LockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot lock cache: %w", err)
+	}
+	defer lock.Release()
+
+	log.S().Infof("repository cache located at %s",
+		color.MagentaString(c.cacheDir),
+	)
+
+	result := &WorkspaceResult{Dir: dir, Members: []*MemberResult{}}
+	fetched := installer.NewFetchSet()
+	errs := []error{}
+	for i, member := range members {
+		log.S().Infof("installing workspace member %s", color.CyanString(ws.Members[i]))
+
+		memberStart := time.Now()
+		report, err := member.installLocked(ctx, opts, fetched)
+		result.Members = append(result.Members, &MemberResult{
+			Dir:    ws.Members[i],
+			Result: newResult(report, memberStart),
+			Err:    err,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ws.Members[i], err))
+			if !opts.KeepGoing || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+	result.Duration = time.Since(start)
+
+	if len(errs) > 0 {
+		return result, fmt.Errorf("cannot install workspace: %w", errors.Join(errs...))
+	}
+
+	log.S().Infof("workspace install success ✅")
+	return result, nil
+}
+
+// member returns a controller for the project in dir, that shares the cache
+// and the options of c.
+func (c *Controller) member(dir string) *Controller {
+	member := *c
+	member.workDir = dir
+	member.root = vending.DirFS(dir)
+	return &member
+}
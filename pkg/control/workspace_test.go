@@ -0,0 +1,74 @@
+package control
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alevinval/vendor-go/pkg/event"
+	"github.com/alevinval/vendor-go/pkg/vending"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWorkspaceDependency(name, url string) *vending.Dependency {
+	dep := vending.NewDependency(url, "master")
+	dep.Name = name
+	dep.Dest = name
+	dep.Filters.AddExtension("proto")
+	return dep
+}
+
+func TestController_InstallWorkspace_FetchesSharedRepositoryOnce(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	sut := newTestProject(t)
+	sut.write(vending.WorkspaceFilename, "members:\n  - one\n  - two\n")
+	one, two := sut.member("one"), sut.member("two")
+	one.saveSpec(newWorkspaceDependency("up", up.dir))
+	two.saveSpec(newWorkspaceDependency("up", up.dir))
+	_, err := sut.controller().InstallWorkspace(context.Background(), InstallOptions{})
+	require.NoError(t, err)
+
+	// Both members need to fetch: one is locked to a commit that the cache
+	// does not have yet, and two to a commit that the fetch does not bring.
+	commit := up.commit(map[string]string{"a.proto": "updated"})
+	for member, commit := range map[*testProject]string{one: commit, two: "0123456789012345678901234567890123456789"} {
+		lock := vending.NewDependencyLock(up.dir, commit)
+		lock.Name = "up"
+		member.saveLock(lock)
+	}
+
+	fetches := &atomic.Int32{}
+	sink := event.SinkFunc(func(e event.Event) {
+		if _, ok := e.(event.FetchStarted); ok {
+			fetches.Add(1)
+		}
+	})
+	result, err := sut.controller(WithEventSink(sink)).InstallWorkspace(context.Background(), InstallOptions{KeepGoing: true})
+
+	require.Error(t, err)
+	assert.Equal(t, int32(1), fetches.Load())
+	assert.NoError(t, result.Members[0].Err)
+	assert.Equal(t, "updated", one.read("vendor/up/a.proto"))
+	assert.Error(t, result.Members[1].Err)
+}
+
+func TestController_InstallWorkspace_ReportsFailedMember(t *testing.T) {
+	up := newUpstream(t, map[string]string{"a.proto": "a"})
+	sut := newTestProject(t)
+	sut.write(vending.WorkspaceFilename, "members:\n  - broken\n  - ok\n")
+	sut.member("broken").saveSpec(newWorkspaceDependency("missing", sut.path("missing")))
+	sut.member("ok").saveSpec(newWorkspaceDependency("up", up.dir))
+
+	result, err := sut.controller().InstallWorkspace(context.Background(), InstallOptions{KeepGoing: true})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken: ")
+	require.Len(t, result.Members, 2)
+	assert.Equal(t, "broken", result.Members[0].Dir)
+	assert.Error(t, result.Members[0].Err)
+	assert.Equal(t, "ok", result.Members[1].Dir)
+	assert.NoError(t, result.Members[1].Err)
+	assert.Equal(t, []*MemberResult{result.Members[0]}, result.Failed())
+	assert.Equal(t, "a", sut.member("ok").read("vendor/up/a.proto"))
+}
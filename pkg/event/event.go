@@ -0,0 +1,149 @@
+// Package event defines the events emitted while vendoring dependencies, so
+// tools that embed vending can follow its progress.
+//
+// Dependencies are vendored in parallel, a Sink receives events from several
+// goroutines at the same time, and must be safe for concurrent use. Events of
+// a single dependency are emitted in order.
+package event
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// Event is emitted while vendoring a dependency. Dependency is the identity
+// of the dependency, its name, or its URL when it has no name.
+type Event interface {
+	DependencyID() string
+}
+
+// DependencyStarted is emitted when a dependency starts being vendored.
+type DependencyStarted struct {
+	Dependency string
+	Revision   string
+}
+
+// CloneStarted is emitted when the repository of a dependency is not in the
+// cache, and has to be cloned.
+type CloneStarted struct {
+	Dependency string
+	URL        string
+}
+
+// FetchStarted is emitted when the repository of a dependency is fetched.
+type FetchStarted struct {
+	Dependency string
+	URL        string
+}
+
+// FetchProgress reports the progress of a clone, or a fetch, as reported by
+// the remote. Stage describes what the remote is doing (eg. "Receiving
+// objects"), Current and Total are zero when the remote did not report them.
+type FetchProgress struct {
+	Dependency string
+	Stage      string
+	Current    int
+	Total      int
+}
+
+// FileCopied is emitted for every file that is vendored, Path is relative to
+// the vendor directory.
+type FileCopied struct {
+	Dependency string
+	Path       string
+}
+
+// DependencyLocked is emitted when a dependency has been vendored at Commit.
+// Tag is set when the commit was resolved from a tag.
+type DependencyLocked struct {
+	Dependency string
+	Commit     string
+	Tag        string
+}
+
+// DependencyFailed is emitted when a dependency cannot be vendored.
+type DependencyFailed struct {
+	Dependency string
+	Err        error
+}
+
+func (e DependencyStarted) DependencyID() string { return e.Dependency }
+func (e CloneStarted) DependencyID() string      { return e.Dependency }
+func (e FetchStarted) DependencyID() string      { return e.Dependency }
+func (e FetchProgress) DependencyID() string     { return e.Dependency }
+func (e FileCopied) DependencyID() string        { return e.Dependency }
+func (e DependencyLocked) DependencyID() string  { return e.Dependency }
+func (e DependencyFailed) DependencyID() string  { return e.Dependency }
+
+// Sink receives events.
+type Sink interface {
+	Emit(Event)
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(Event)
+
+func (f SinkFunc) Emit(e Event) {
+	f(e)
+}
+
+// Discard is a Sink that ignores every event.
+var Discard Sink = SinkFunc(func(Event) {})
+
+// Multi returns a Sink that emits every event to all the sinks, in order.
+func Multi(sinks ...Sink) Sink {
+	return SinkFunc(func(e Event) {
+		for _, sink := range sinks {
+			sink.Emit(e)
+		}
+	})
+}
+
+var progressLine = regexp.MustCompile(`^(.+?):\s+\d+% \((\d+)/(\d+)\)`)
+
+// ProgressWriter returns an io.Writer for the progress messages of a git
+// remote, it emits a FetchProgress for every line that is written.
+func ProgressWriter(sink Sink, dependency string) io.Writer {
+	return &progressWriter{sink: sink, dependency: dependency}
+}
+
+type progressWriter struct {
+	sink       Sink
+	dependency string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write buffers the data until a full line, terminated by "\r" or "\n", is
+// available.
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(bytes.TrimSpace(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+		if line != "" {
+			w.sink.Emit(parseProgress(w.dependency, line))
+		}
+	}
+}
+
+func parseProgress(dependency, line string) FetchProgress {
+	progress := FetchProgress{Dependency: dependency, Stage: line}
+	if m := progressLine.FindStringSubmatch(line); m != nil {
+		progress.Stage = m[1]
+		progress.Current, _ = strconv.Atoi(m[2])
+		progress.Total, _ = strconv.Atoi(m[3])
+	}
+	return progress
+}
@@ -0,0 +1,44 @@
+package event
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) Emit(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func TestProgressWriter(t *testing.T) {
+	sink := &recorder{}
+	sut := ProgressWriter(sink, "some-dep")
+
+	sut.Write([]byte("Counting objects:  50% (1/2)\rCounting obj"))
+	sut.Write([]byte("ects: 100% (2/2), done.\n"))
+	sut.Write([]byte("Total 2 (delta 0)\n"))
+
+	assert.Equal(t, []Event{
+		FetchProgress{Dependency: "some-dep", Stage: "Counting objects", Current: 1, Total: 2},
+		FetchProgress{Dependency: "some-dep", Stage: "Counting objects", Current: 2, Total: 2},
+		FetchProgress{Dependency: "some-dep", Stage: "Total 2 (delta 0)"},
+	}, sink.events)
+}
+
+func TestMulti(t *testing.T) {
+	one, two := &recorder{}, &recorder{}
+	sut := Multi(one, two, Discard)
+
+	sut.Emit(FileCopied{Dependency: "some-dep", Path: "some-path"})
+
+	assert.Equal(t, []Event{FileCopied{Dependency: "some-dep", Path: "some-path"}}, one.events)
+	assert.Equal(t, one.events, two.events)
+}
@@ -19,10 +19,15 @@ var (
 	// logger keeps reference to the current logger that will be used by the
 	// vendoring tool.
 	logger *zap.Logger
+
+	// builtinLogger is the logger built by the library, as opposed to one
+	// injected with SetLogger.
+	builtinLogger *zap.Logger
 )
 
 func init() {
-	logger = zap.Must(getDefaultCfg().Build())
+	builtinLogger = zap.Must(getDefaultCfg().Build())
+	logger = builtinLogger
 }
 
 // S returns the zap sugared logger from the current logger instance.
@@ -35,6 +40,19 @@ func SetLogger(newLogger *zap.Logger) {
 	logger = newLogger
 }
 
+// UseStderr makes the default logger write to stderr, leaving stdout for the
+// machine-readable output of the commands. It has no effect on a logger that
+// was injected with SetLogger.
+func UseStderr() {
+	cfg := getDefaultCfg()
+	cfg.OutputPaths = []string{"stderr"}
+	defaultLogger := zap.Must(cfg.Build())
+	if logger == builtinLogger {
+		logger = defaultLogger
+	}
+	builtinLogger = defaultLogger
+}
+
 func getDefaultCfg() zap.Config {
 	rawJSON := []byte(`{
 		"level": "info",
@@ -1,20 +1,92 @@
 package vending
 
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
 // Dependency holds relevant information related to a dependency that has to be
 // vendored. This model directly maps to the serialized YAML, for dependencies.
+//
+// Name is optional, and identifies the dependency. When it is not set, the URL
+// is used instead. Naming dependencies allows vendoring the same repository
+// more than once, for instance two branches into different destinations.
+//
+// Ref, when set, takes precedence over Branch and can be the name of a branch,
+// a tag, a commit, or a semver constraint (eg. "^1.4" or "~2.0.3") that is
+// resolved against the tags of the repository.
+//
+// Dest and Strip customize where the files of the dependency are copied. Strip
+// removes a leading directory from the paths of the repository, and Dest is
+// the directory, relative to the vendor directory, where files are copied.
+//
+// Priority decides which dependency vendors a path when several of them have
+// a file with that path, the highest one wins. Vendoring different files into
+// the same path with the same priority is an error.
+//
+// Pinned dependencies are not updated, Pin records why, and until when.
 type Dependency struct {
-	URL     string   `yaml:"url"`
-	Branch  string   `yaml:"branch"`
-	Filters *Filters `yaml:",inline"`
-	Pinned  bool     `yaml:"pinned,omitempty"`
+	Name     string   `yaml:"name,omitempty"`
+	URL      string   `yaml:"url"`
+	Branch   string   `yaml:"branch,omitempty"`
+	Ref      string   `yaml:"ref,omitempty"`
+	Dest     string   `yaml:"dest,omitempty"`
+	Strip    string   `yaml:"strip,omitempty"`
+	Priority int      `yaml:"priority,omitempty"`
+	Filters  *Filters `yaml:",inline"`
+	Pinned   bool     `yaml:"pinned,omitempty"`
+	Pin      *Pin     `yaml:"pin,omitempty"`
+}
+
+// PinDateFormat is the format of the Until date of a Pin.
+const PinDateFormat = time.DateOnly
+
+// Pin holds the metadata of a pinned dependency. Reason describes why it is
+// pinned, and Until is the date, formatted as PinDateFormat, after which the
+// pin has to be revisited. Both are optional.
+type Pin struct {
+	Reason string `yaml:"reason,omitempty"`
+	Until  string `yaml:"until,omitempty"`
+}
+
+// UntilDate parses the Until date of the pin. It returns false when the pin
+// has no date.
+func (p *Pin) UntilDate() (time.Time, bool, error) {
+	if p == nil || p.Until == "" {
+		return time.Time{}, false, nil
+	}
+	until, err := time.Parse(PinDateFormat, p.Until)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("until %q must be a date formatted as YYYY-MM-DD", p.Until)
+	}
+	return until, true, nil
 }
 
 // DependencyLock holds relevant information of a dependency that has been
 // locked to a specific commit. This model directly maps to the serialized YAML
 // for locked dependencies.
+//
+// Branch, Ref and FiltersDigest record the spec inputs the dependency was
+// locked from, so a lock that is out of date with the spec can be detected.
+//
+// Files maps the path of every vendored file, relative to the vendor
+// directory, to the digest of its contents. Digest is the aggregate digest of
+// all of them, see DigestFiles. Shadowed lists the paths that the dependency
+// also vendors, but that dependencies with higher priority vendor instead.
 type DependencyLock struct {
-	URL    string `yaml:"url"`
-	Commit string `yaml:"commit"`
+	Name          string            `yaml:"name,omitempty"`
+	URL           string            `yaml:"url"`
+	Commit        string            `yaml:"commit"`
+	Tag           string            `yaml:"tag,omitempty"`
+	Branch        string            `yaml:"branch,omitempty"`
+	Ref           string            `yaml:"ref,omitempty"`
+	FiltersDigest string            `yaml:"filters_digest,omitempty"`
+	Digest        string            `yaml:"digest,omitempty"`
+	Files         map[string]string `yaml:"files,omitempty"`
+	Shadowed      []string          `yaml:"shadowed,omitempty"`
 }
 
 // NewDependency allocates a Dependency, with a default Filters instance.
@@ -26,7 +98,7 @@ func NewDependency(url, branch string) *Dependency {
 	}
 }
 
-// NewDependencyLock allocates a new DependencyLock
+// NewDependencyLock allocates a new DependencyLock.
 func NewDependencyLock(url, commit string) *DependencyLock {
 	return &DependencyLock{
 		URL:    url,
@@ -34,18 +106,131 @@ func NewDependencyLock(url, commit string) *DependencyLock {
 	}
 }
 
-// Update changes the URL, Branch and Filters fields of the dependency by the
-// fields of another one. This clones the Filters to ensure there's no shared
-// data with the other Dependency.
+// ID returns the identity of the dependency, this is Name when it is set, URL
+// otherwise.
+func (d *Dependency) ID() string {
+	return identity(d.Name, d.URL)
+}
+
+// ID returns the identity of the locked dependency, this is Name when it is
+// set, URL otherwise.
+func (d *DependencyLock) ID() string {
+	return identity(d.Name, d.URL)
+}
+
+// SetFiles records the digests of the vendored files, and updates the
+// aggregate digest accordingly.
+func (d *DependencyLock) SetFiles(files map[string]string) {
+	d.Files = files
+	d.Digest = DigestFiles(files)
+}
+
+// Update changes the URL, Branch, Ref, Dest, Strip, Priority and Filters fields
+// of the dependency by the fields of another one. This clones the Filters to
+// ensure there's no shared data with the other Dependency.
 func (d *Dependency) Update(other *Dependency) {
 	d.URL = other.URL
 	d.Branch = other.Branch
+	d.Ref = other.Ref
+	d.Dest = other.Dest
+	d.Strip = other.Strip
+	d.Priority = other.Priority
 	d.Filters = other.Filters.Clone()
 }
 
+// PinExpired returns whether the dependency is pinned, and the Until date of
+// its pin is before the day of now. A pin is still valid on its Until date.
+func (d *Dependency) PinExpired(now time.Time) bool {
+	if !d.Pinned {
+		return false
+	}
+	until, ok, err := d.Pin.UntilDate()
+	if err != nil || !ok {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return until.Before(today)
+}
+
+// Revision returns the reference that has to be vendored, this is Ref when it
+// is set, Branch otherwise.
+func (d *Dependency) Revision() string {
+	if d.Ref != "" {
+		return d.Ref
+	}
+	return d.Branch
+}
+
+// Host returns the host that serves the repository of the dependency, it
+// understands URLs as well as the scp-like syntax of git (eg.
+// "git@github.com:org/repo.git"). Local repositories have an empty host.
+func (d *Dependency) Host() string {
+	if strings.Contains(d.URL, "://") {
+		u, err := url.Parse(d.URL)
+		if err != nil || u.Scheme == "file" {
+			return ""
+		}
+		return strings.ToLower(u.Hostname())
+	}
+
+	// scp-like syntax: [user@]host:path
+	if i := strings.Index(d.URL, ":"); i > 0 && !strings.Contains(d.URL[:i], "/") {
+		host := d.URL[:i]
+		if j := strings.LastIndex(host, "@"); j >= 0 {
+			host = host[j+1:]
+		}
+		return strings.ToLower(host)
+	}
+	return ""
+}
+
+// CheckPaths returns an error when Dest or Strip are not local paths, this
+// prevents writing files outside of the vendor directory.
+func (d *Dependency) CheckPaths() error {
+	if d.Dest != "" && !filepath.IsLocal(d.Dest) {
+		return fmt.Errorf("dest %q must be a relative path inside the vendor directory", d.Dest)
+	}
+	if d.Strip != "" && !filepath.IsLocal(d.Strip) {
+		return fmt.Errorf("strip %q must be a relative path inside the repository", d.Strip)
+	}
+	return nil
+}
+
+// TargetPath maps a path relative to the root of the dependency repository to
+// the path, relative to the vendor directory, where it has to be copied. Paths
+// outside of the Strip directory are kept as they are.
+func (d *Dependency) TargetPath(pathRel string) (string, error) {
+	if err := d.CheckPaths(); err != nil {
+		return "", err
+	}
+
+	if d.Strip != "" {
+		rel, err := filepath.Rel(d.Strip, pathRel)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			if rel == "." {
+				rel = filepath.Base(pathRel)
+			}
+			pathRel = rel
+		}
+	}
+
+	target := filepath.Join(d.Dest, pathRel)
+	if !filepath.IsLocal(target) {
+		return "", fmt.Errorf("path %q escapes the vendor directory", pathRel)
+	}
+	return target, nil
+}
+
 func (d *Dependency) applyPreset(preset Preset) {
 	if d.Filters == nil {
 		d.Filters = NewFilters()
 	}
 	d.Filters.ApplyPresetForDependency(preset, d)
 }
+
+func identity(name, url string) string {
+	if name != "" {
+		return name
+	}
+	return url
+}
@@ -2,6 +2,7 @@ package vending
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -25,3 +26,125 @@ func TestDependencyUpdate(t *testing.T) {
 	assert.Equal(t, other.Filters.Targets, dep.Filters.Targets)
 	assert.Equal(t, other.Filters.Ignores, dep.Filters.Ignores)
 }
+
+func TestDependencyUpdate_CopiesDestStripAndPriority(t *testing.T) {
+	dep := NewDependency("some-url", "some-branch")
+
+	other := NewDependency("some-url", "some-branch")
+	other.Dest = "some-dest"
+	other.Strip = "some-strip"
+	other.Priority = 2
+
+	dep.Update(other)
+
+	assert.Equal(t, "some-dest", dep.Dest)
+	assert.Equal(t, "some-strip", dep.Strip)
+	assert.Equal(t, 2, dep.Priority)
+}
+
+func TestDependency_TargetPath(t *testing.T) {
+	cases := []struct {
+		dest, strip, path, expected string
+	}{
+		{"", "", "pkg/proto/ledger.proto", "pkg/proto/ledger.proto"},
+		{"third_party/ledger", "", "pkg/proto/ledger.proto", "third_party/ledger/pkg/proto/ledger.proto"},
+		{"", "pkg/proto", "pkg/proto/ledger.proto", "ledger.proto"},
+		{"third_party/ledger", "pkg/proto", "pkg/proto/v1/ledger.proto", "third_party/ledger/v1/ledger.proto"},
+		{"third_party/ledger", "pkg/proto", "README.md", "third_party/ledger/README.md"},
+		{"third_party/ledger", "pkg/proto", "pkg/protobuf/a.proto", "third_party/ledger/pkg/protobuf/a.proto"},
+	}
+
+	for _, c := range cases {
+		dep := NewDependency("some-url", "some-branch")
+		dep.Dest = c.dest
+		dep.Strip = c.strip
+
+		actual, err := dep.TargetPath(c.path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, actual)
+	}
+}
+
+func TestDependency_CheckPaths_RejectsTraversal(t *testing.T) {
+	for _, path := range []string{"../outside", "/absolute", "a/../../outside"} {
+		dep := NewDependency("some-url", "some-branch")
+		dep.Dest = path
+		assert.Error(t, dep.CheckPaths())
+
+		_, err := dep.TargetPath("file.txt")
+		assert.Error(t, err)
+
+		dep = NewDependency("some-url", "some-branch")
+		dep.Strip = path
+		assert.Error(t, dep.CheckPaths())
+	}
+}
+
+func TestDependency_Revision_PrefersRef(t *testing.T) {
+	dep := NewDependency("some-url", "some-branch")
+	assert.Equal(t, "some-branch", dep.Revision())
+
+	dep.Ref = "^1.4"
+	assert.Equal(t, "^1.4", dep.Revision())
+}
+
+func TestDependency_ID_FallsBackToURL(t *testing.T) {
+	dep := NewDependency("some-url", "some-branch")
+	assert.Equal(t, "some-url", dep.ID())
+
+	dep.Name = "some-name"
+	assert.Equal(t, "some-name", dep.ID())
+
+	depLock := NewDependencyLock("some-url", "some-commit")
+	assert.Equal(t, "some-url", depLock.ID())
+
+	depLock.Name = "some-name"
+	assert.Equal(t, "some-name", depLock.ID())
+}
+
+func TestDependency_Host(t *testing.T) {
+	for url, expected := range map[string]string{
+		"https://github.com/org/repo":     "github.com",
+		"https://GitHub.com:443/org/repo": "github.com",
+		"ssh://git@git.example.com/repo":  "git.example.com",
+		"git@github.com:org/repo.git":     "github.com",
+		"git.example.com:repo.git":        "git.example.com",
+		"file:///tmp/repo":                "",
+		"/tmp/repo":                       "",
+		"some-url":                        "",
+	} {
+		dep := NewDependency(url, "some-branch")
+		assert.Equal(t, expected, dep.Host(), url)
+	}
+}
+
+func TestDependency_PinExpired(t *testing.T) {
+	now := time.Date(2026, time.March, 10, 15, 0, 0, 0, time.UTC)
+	dep := NewDependency("some-url", "some-branch")
+	dep.Pin = &Pin{Until: "2026-03-09"}
+	assert.False(t, dep.PinExpired(now), "not pinned")
+
+	dep.Pinned = true
+	assert.True(t, dep.PinExpired(now))
+
+	dep.Pin.Until = "2026-03-10"
+	assert.False(t, dep.PinExpired(now), "valid on its until date")
+
+	dep.Pin = &Pin{Reason: "some-reason"}
+	assert.False(t, dep.PinExpired(now), "no until date")
+}
+
+func TestPin_UntilDate(t *testing.T) {
+	until, ok, err := (&Pin{Until: "2026-03-09"}).UntilDate()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2026, time.March, 9, 0, 0, 0, 0, time.UTC), until)
+
+	_, ok, err = (*Pin)(nil).UntilDate()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = (&Pin{Until: "09/03/2026"}).UntilDate()
+	assert.Error(t, err)
+}
@@ -0,0 +1,139 @@
+package vending
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const digestPrefix = "sha256:"
+
+// VerifyReport lists the differences between the files in the vendor
+// directory and the digests recorded in the SpecLock. Paths are relative to
+// the vendor directory.
+type VerifyReport struct {
+	Added      []string
+	Modified   []string
+	Missing    []string
+	Unverified []string
+}
+
+// OK returns whether the vendor directory matches the SpecLock.
+func (r *VerifyReport) OK() bool {
+	return len(r.Added) == 0 && len(r.Modified) == 0 &&
+		len(r.Missing) == 0 && len(r.Unverified) == 0
+}
+
+// NewDigest returns a writer that hashes everything written to it, and a
+// function that returns the digest, as recorded in the lock file.
+func NewDigest() (io.Writer, func() string) {
+	h := sha256.New()
+	return h, func() string {
+		return digestPrefix + hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+// HashFile returns the digest of the contents of a file.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	w, sum := NewDigest()
+	if _, err := io.Copy(w, f); err != nil {
+		return "", fmt.Errorf("cannot hash %q: %w", path, err)
+	}
+	return sum(), nil
+}
+
+// DigestFiles returns the aggregate digest of a set of file digests, it does
+// not depend on the order in which the files were collected.
+func DigestFiles(files map[string]string) string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	w, sum := NewDigest()
+	for _, path := range paths {
+		fmt.Fprintf(w, "%s\x00%s\n", path, files[path])
+	}
+	return sum()
+}
+
+// Verify recomputes the digests of the files in the vendor directory, and
+// compares them with the ones recorded for each locked dependency. It works
+// offline, the repositories of the dependencies are not needed.
+func (s *SpecLock) Verify(vendorDir string) (*VerifyReport, error) {
+	report := &VerifyReport{}
+
+	expected := map[string]string{}
+	for _, dep := range s.Deps {
+		// A dependency without files still has a digest, only the ones that
+		// were locked before digests were recorded cannot be verified.
+		if dep.Digest == "" {
+			report.Unverified = append(report.Unverified, dep.ID())
+			continue
+		}
+		for path, digest := range dep.Files {
+			expected[path] = digest
+		}
+	}
+
+	found := map[string]bool{}
+	err := filepath.WalkDir(vendorDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == vendorDir {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(vendorDir, path)
+		if err != nil {
+			return fmt.Errorf("cannot get relative path: %w", err)
+		}
+		rel = filepath.ToSlash(rel)
+		found[rel] = true
+
+		digest, ok := expected[rel]
+		if !ok {
+			report.Added = append(report.Added, rel)
+			return nil
+		}
+
+		actual, err := HashFile(path)
+		if err != nil {
+			return err
+		}
+		if actual != digest {
+			report.Modified = append(report.Modified, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot walk vendor dir: %w", err)
+	}
+
+	for path := range expected {
+		if !found[path] {
+			report.Missing = append(report.Missing, path)
+		}
+	}
+
+	sort.Strings(report.Added)
+	sort.Strings(report.Modified)
+	sort.Strings(report.Missing)
+	return report, nil
+}
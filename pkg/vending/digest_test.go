@@ -0,0 +1,81 @@
+package vending
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigestFiles_IsStable(t *testing.T) {
+	one := map[string]string{"a": "sha256:1", "b": "sha256:2"}
+	two := map[string]string{"b": "sha256:2", "a": "sha256:1"}
+
+	assert.Equal(t, DigestFiles(one), DigestFiles(two))
+	assert.NotEqual(t, DigestFiles(one), DigestFiles(map[string]string{"a": "sha256:2", "b": "sha256:1"}))
+}
+
+func TestSpecLock_Verify(t *testing.T) {
+	dir := t.TempDir()
+
+	writeVerifyFile(t, dir, "a/unchanged.txt", "unchanged")
+	writeVerifyFile(t, dir, "a/modified.txt", "original")
+	writeVerifyFile(t, dir, "b/missing.txt", "missing")
+
+	files := map[string]string{}
+	for _, path := range []string{"a/unchanged.txt", "a/modified.txt", "b/missing.txt"} {
+		digest, err := HashFile(filepath.Join(dir, path))
+		assert.NoError(t, err)
+		files[path] = digest
+	}
+
+	depLock := NewDependencyLock("some-url", "some-commit")
+	depLock.SetFiles(files)
+	sut := NewSpecLock(nil)
+	sut.AddDependencyLock(depLock)
+
+	report, err := sut.Verify(dir)
+	assert.NoError(t, err)
+	assert.True(t, report.OK())
+
+	writeVerifyFile(t, dir, "a/modified.txt", "modified")
+	writeVerifyFile(t, dir, "a/added.txt", "added")
+	os.Remove(filepath.Join(dir, "b/missing.txt"))
+
+	report, err = sut.Verify(dir)
+	assert.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, []string{"a/added.txt"}, report.Added)
+	assert.Equal(t, []string{"a/modified.txt"}, report.Modified)
+	assert.Equal(t, []string{"b/missing.txt"}, report.Missing)
+}
+
+func TestSpecLock_Verify_WithoutDigests_IsUnverified(t *testing.T) {
+	sut := NewSpecLock(nil)
+	sut.AddDependencyLock(NewDependencyLock("some-url", "some-commit"))
+
+	report, err := sut.Verify(t.TempDir())
+
+	assert.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, []string{"some-url"}, report.Unverified)
+}
+
+func TestSpecLock_Verify_WithoutFiles_IsVerified(t *testing.T) {
+	depLock := NewDependencyLock("some-url", "some-commit")
+	depLock.SetFiles(map[string]string{})
+	sut := NewSpecLock(nil)
+	sut.AddDependencyLock(depLock)
+
+	report, err := sut.Verify(t.TempDir())
+
+	assert.NoError(t, err)
+	assert.True(t, report.OK())
+}
+
+func writeVerifyFile(t *testing.T, dir, path, contents string) {
+	path = filepath.Join(dir, path)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	assert.NoError(t, os.WriteFile(path, []byte(contents), os.ModePerm))
+}
@@ -0,0 +1,49 @@
+package vending
+
+import "errors"
+
+// Errors returned by the vending tool, they are wrapped with the details of
+// each failure and can be matched with errors.Is.
+var (
+	// ErrSpecNotFound is returned when the spec file does not exist.
+	ErrSpecNotFound = errors.New("spec not found")
+
+	// ErrWorkspaceNotFound is returned when no workspace file exists in the
+	// working directory, nor in any of its parents.
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+
+	// ErrSpecInvalid is returned when the spec file cannot be parsed, or
+	// validating it reported errors.
+	ErrSpecInvalid = errors.New("spec is invalid")
+
+	// ErrOutputCollision is returned when several dependencies, with the same
+	// priority, vendor different files into the same path.
+	ErrOutputCollision = errors.New("output path collision")
+
+	// ErrDependencyNotFound is returned when a dependency is not in the spec.
+	ErrDependencyNotFound = errors.New("dependency not found")
+
+	// ErrPinExpired is returned when a pinned dependency is past the date
+	// its pin had to be revisited.
+	ErrPinExpired = errors.New("pin expired")
+
+	// ErrLockOutOfDate is returned when the lockfile is not in sync with the
+	// spec, and it cannot be updated.
+	ErrLockOutOfDate = errors.New("lockfile is out of date")
+
+	// ErrNetwork is returned when a repository cannot be cloned or fetched,
+	// because it cannot be reached or the credentials are rejected.
+	ErrNetwork = errors.New("cannot reach repository")
+
+	// ErrVerifyMismatch is returned when the vendored files do not match the
+	// digests recorded in the lockfile.
+	ErrVerifyMismatch = errors.New("vendored files do not match the lockfile")
+
+	// ErrUnvendoredFiles is returned, together with ErrVerifyMismatch, when
+	// the vendor directory has files that no locked dependency vendors.
+	ErrUnvendoredFiles = errors.New("files that no dependency vendors")
+
+	// ErrLockTimeout is returned when the cache lock could not be acquired in
+	// time, because another instance of the tool is holding it.
+	ErrLockTimeout = errors.New("timed out waiting for lock")
+)
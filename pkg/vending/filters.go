@@ -4,6 +4,10 @@ import (
 	"sort"
 )
 
+// AnyExtension can be used in the extensions list to select files regardless
+// of their extension, including files without one.
+const AnyExtension = "*"
+
 // Filters is a collection type. When vendoring dependencies we look if the
 // file paths are copied or ignored, or whether the extension is supported.
 // Filters are directly serialized into the output YAML.
@@ -14,10 +18,24 @@ import (
 // supports both global customization, and specific granular configurations
 // for each dependency. For this reason, we often need to combine multiple
 // Filters before we're able to vendor a dependency.
+//
+// Targets and Ignores are glob patterns matched against slash separated paths,
+// relative to the repository root. Matching is path-segment aware: "*" and "?"
+// never cross a "/", and "**" matches any number of directories. A pattern
+// that matches a directory also matches everything beneath it, so "doc"
+// matches "doc/index.md" but neither "docs/index.md" nor "doc.go".
+//
+// ExportIgnore leaves out the paths that the .gitattributes files of the
+// repository mark with export-ignore, like git archive does. VendorIgnore
+// leaves out the paths listed in the .vendorignore files of the repository,
+// which follow the syntax of .gitignore. Both are enabled when either the spec
+// or the dependency enables them.
 type Filters struct {
-	Extensions []string `yaml:"extensions,omitempty"`
-	Targets    []string `yaml:"targets,omitempty"`
-	Ignores    []string `yaml:"ignores,omitempty"`
+	Extensions   []string `yaml:"extensions,omitempty"`
+	Targets      []string `yaml:"targets,omitempty"`
+	Ignores      []string `yaml:"ignores,omitempty"`
+	ExportIgnore bool     `yaml:"export_ignore,omitempty"`
+	VendorIgnore bool     `yaml:"vendor_ignore,omitempty"`
 }
 
 // NewFilters allocates a Filters instance with empty lists initialization.
@@ -54,6 +72,8 @@ func (f *Filters) ApplyFilters(filters *Filters) *Filters {
 	f.Extensions = sortedUnion(f.Extensions, filters.Extensions)
 	f.Targets = sortedUnion(f.Targets, filters.Targets)
 	f.Ignores = sortedUnion(f.Ignores, filters.Ignores)
+	f.ExportIgnore = f.ExportIgnore || filters.ExportIgnore
+	f.VendorIgnore = f.VendorIgnore || filters.VendorIgnore
 	return f
 }
 
@@ -76,20 +96,25 @@ func (f *Filters) ApplyPresetForDependency(preset Preset, dep *Dependency) *Filt
 		ApplyFilters(preset.GetFiltersForDependency(dep))
 }
 
-// Clear resets the lists.
+// Clear resets the lists, and disables the ignore files.
 func (f *Filters) Clear() *Filters {
 	f.Extensions = []string{}
 	f.Targets = []string{}
 	f.Ignores = []string{}
+	f.ExportIgnore = false
+	f.VendorIgnore = false
 	return f
 }
 
 // Clone allocates a new instance, and clones the contents of the current one.
 func (f *Filters) Clone() *Filters {
-	return NewFilters().
+	clone := NewFilters().
 		AddExtension(f.Extensions...).
 		AddTarget(f.Targets...).
 		AddIgnore(f.Ignores...)
+	clone.ExportIgnore = f.ExportIgnore
+	clone.VendorIgnore = f.VendorIgnore
+	return clone
 }
 
 func sortedUnion(a, b []string) []string {
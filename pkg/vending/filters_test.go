@@ -62,6 +62,7 @@ func TestFilters_Clone_ReturnsNewInstance(t *testing.T) {
 		AddExtension("ext-1").
 		AddTarget("target-1").
 		AddIgnore("ignore-1")
+	sut.ExportIgnore = true
 
 	clone := sut.Clone()
 	assert.Equal(t, sut, clone)
@@ -75,6 +76,7 @@ func TestFilters_Clear_ThenClearsLists(t *testing.T) {
 		AddExtension("ext-1").
 		AddTarget("target-1").
 		AddIgnore("ignore-1")
+	sut.VendorIgnore = true
 
 	assert.NotEqual(t, NewFilters(), sut)
 
@@ -82,3 +84,15 @@ func TestFilters_Clear_ThenClearsLists(t *testing.T) {
 
 	assert.Equal(t, NewFilters(), sut)
 }
+
+func TestFilters_ApplyFilters_EnablesIgnoreFiles(t *testing.T) {
+	other := NewFilters()
+	other.ExportIgnore = true
+
+	sut := NewFilters().ApplyFilters(other)
+	assert.True(t, sut.ExportIgnore)
+	assert.False(t, sut.VendorIgnore)
+
+	sut.ApplyFilters(NewFilters())
+	assert.True(t, sut.ExportIgnore)
+}
@@ -0,0 +1,41 @@
+package vending
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/alevinval/vendor-go/internal/txn"
+)
+
+var _ FS = DirFS("")
+
+// FS is the filesystem where Spec and SpecLock read and write their files.
+// Filenames are the ones of the preset, relative to the root of the FS.
+type FS interface {
+	// ReadFile returns the contents of the file
+	ReadFile(name string) ([]byte, error)
+
+	// WriteFile replaces the contents of the file, creating it if needed
+	WriteFile(name string, data []byte) error
+}
+
+// DirFS is an FS rooted at a directory of the OS filesystem, the empty
+// string being the working directory of the process. Absolute filenames are
+// not resolved against the root. Files are replaced atomically.
+type DirFS string
+
+// Path returns the path of name in the OS filesystem.
+func (dir DirFS) Path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(string(dir), name)
+}
+
+func (dir DirFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(dir.Path(name))
+}
+
+func (dir DirFS) WriteFile(name string, data []byte) error {
+	return txn.WriteFileAtomic(dir.Path(name), data)
+}
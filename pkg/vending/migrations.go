@@ -0,0 +1,87 @@
+package vending
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Migration upgrades the YAML documents produced by versions of the tool older
+// than Version. Migrations are applied in order, one version after the other,
+// so each of them only needs to know about the schema right before it.
+//
+// Spec and Lock receive the root mapping node of the document, and return a
+// human readable description of every change they made. Either can be nil when
+// the migration does not affect that document.
+type Migration struct {
+	Version     string
+	Description string
+	Spec        func(root *yaml.Node) ([]string, error)
+	Lock        func(root *yaml.Node) ([]string, error)
+}
+
+// MigrationReport describes what changed when loading a spec, or spec lock,
+// that was produced by an older version of the tool.
+type MigrationReport struct {
+	From    string
+	To      string
+	Changes []string
+}
+
+// IsEmpty returns whether nothing changed.
+func (r *MigrationReport) IsEmpty() bool {
+	return r.From == r.To && len(r.Changes) == 0
+}
+
+// migrations is the registry of all the schema migrations, sorted by version.
+// It is empty on purpose: the schema has not changed since v0.5.1.
+var migrations = []Migration{}
+
+type migrationFunc = func(m Migration) func(root *yaml.Node) ([]string, error)
+
+func specMigration(m Migration) func(root *yaml.Node) ([]string, error) {
+	return m.Spec
+}
+
+func lockMigration(m Migration) func(root *yaml.Node) ([]string, error) {
+	return m.Lock
+}
+
+// applyMigrations runs, in version order, the migrations that are newer than
+// the version of the document and not newer than the current VERSION.
+func applyMigrations(doc *yaml.Node, selectFn migrationFunc) (*MigrationReport, error) {
+	root := documentRoot(doc)
+	from := ""
+	if version := mappingValue(root, "version"); version != nil {
+		from = version.Value
+	}
+	report := &MigrationReport{From: from, To: from}
+
+	pending := []Migration{}
+	for _, m := range migrations {
+		if compareVersions(m.Version, from) > 0 && compareVersions(m.Version, VERSION) <= 0 {
+			pending = append(pending, m)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return compareVersions(pending[i].Version, pending[j].Version) < 0
+	})
+
+	for _, m := range pending {
+		fn := selectFn(m)
+		if fn == nil || root == nil {
+			continue
+		}
+		changes, err := fn(root)
+		if err != nil {
+			return nil, fmt.Errorf("cannot migrate to %s (%s): %w", m.Version, m.Description, err)
+		}
+		report.Changes = append(report.Changes, changes...)
+	}
+
+	if compareVersions(from, VERSION) < 0 {
+		report.To = VERSION
+	}
+	return report, nil
+}
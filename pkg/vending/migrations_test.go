@@ -0,0 +1,93 @@
+package vending
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestApplyMigrations_RunsPendingMigrationsInOrder(t *testing.T) {
+	defer withMigrations([]Migration{
+		{Version: "v999.0.0", Spec: appendChange("v999.0.0")},
+		{Version: "v0.2.0", Spec: appendChange("v0.2.0")},
+		{Version: "v0.5.1", Spec: appendChange("v0.5.1")},
+		{Version: "v0.4.0", Spec: appendChange("v0.4.0")},
+		{Version: "v0.5.0", Lock: appendChange("lock-only")},
+	})()
+
+	doc := parseDoc(t, "version: v0.4.0\n")
+
+	report, err := applyMigrations(doc, specMigration)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "v0.4.0", report.From)
+	assert.Equal(t, VERSION, report.To)
+	assert.Equal(t, []string{"v0.5.1"}, report.Changes)
+}
+
+func TestApplyMigrations_WhenUpToDate_IsEmpty(t *testing.T) {
+	doc := parseDoc(t, "version: "+VERSION+"\n")
+
+	report, err := applyMigrations(doc, specMigration)
+
+	assert.NoError(t, err)
+	assert.True(t, report.IsEmpty())
+}
+
+func TestApplyMigrations_WhenNewer_DoesNotMigrate(t *testing.T) {
+	doc := parseDoc(t, "version: v999.0.0\n")
+
+	report, err := applyMigrations(doc, specMigration)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "v999.0.0", report.To)
+	assert.True(t, report.IsEmpty())
+}
+
+func TestSpec_LoadAndMigrate(t *testing.T) {
+	defer withMigrations([]Migration{
+		{Version: "v0.5.0", Spec: func(root *yaml.Node) ([]string, error) {
+			mappingValue(root, "deps").Content[0].Content[1].Value = "migrated-url"
+			return []string{"migrated"}, nil
+		}},
+	})()
+	fsys := newTestFS(t)
+
+	err := fsys.WriteFile(testPreset.GetSpecFilename(), []byte(`version: v0.4.0
+preset: test-preset
+deps:
+  - url: some-url
+`))
+	assert.NoError(t, err)
+
+	sut := NewSpec(testPreset).WithFS(fsys)
+	report, err := sut.LoadAndMigrate()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "v0.4.0", report.From)
+	assert.Equal(t, VERSION, report.To)
+	assert.Equal(t, []string{"migrated"}, report.Changes)
+	assert.Equal(t, VERSION, sut.Version)
+	assert.Equal(t, "migrated-url", sut.Deps[0].URL)
+}
+
+func withMigrations(registry []Migration) func() {
+	previous := migrations
+	migrations = registry
+	return func() {
+		migrations = previous
+	}
+}
+
+func appendChange(change string) func(*yaml.Node) ([]string, error) {
+	return func(*yaml.Node) ([]string, error) {
+		return []string{change}, nil
+	}
+}
+
+func parseDoc(t *testing.T, data string) *yaml.Node {
+	doc := &yaml.Node{}
+	assert.NoError(t, yaml.Unmarshal([]byte(data), doc))
+	return doc
+}
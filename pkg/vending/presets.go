@@ -8,6 +8,11 @@ import (
 )
 
 var _ Preset = (*DefaultPreset)(nil)
+var _ ConcurrencyPreset = (*DefaultPreset)(nil)
+
+// DefaultJobs is the number of dependencies vendored at the same time, unless
+// the preset or the command line say otherwise.
+const DefaultJobs = 8
 
 // Preset interface used to customize the behavior of the vendor library.
 // It allows customizing anything you need, like the names of the spec and
@@ -39,6 +44,69 @@ type Preset interface {
 	GetCacheDir() string
 }
 
+// ConcurrencyPreset can optionally be implemented by a Preset to customize how
+// many dependencies are vendored at the same time.
+type ConcurrencyPreset interface {
+	// GetJobs returns the maximum number of dependencies vendored at the same
+	// time
+	GetJobs() int
+
+	// GetJobsPerHost returns the maximum number of dependencies fetched from
+	// the same host at the same time, zero means no limit
+	GetJobsPerHost() int
+}
+
+// Concurrency returns the number of jobs, and jobs per host, of the preset.
+// Presets that do not implement ConcurrencyPreset get the defaults.
+func Concurrency(preset Preset) (jobs int, jobsPerHost int) {
+	jobs = DefaultJobs
+	if p, ok := unwrapPreset(preset).(ConcurrencyPreset); ok {
+		if n := p.GetJobs(); n > 0 {
+			jobs = n
+		}
+		jobsPerHost = max(p.GetJobsPerHost(), 0)
+	}
+	return jobs, jobsPerHost
+}
+
+// WithFilenames returns a Preset that behaves like preset, but uses other
+// spec and lock filenames. Empty filenames keep the ones of preset.
+func WithFilenames(preset Preset, specFilename, lockFilename string) Preset {
+	if specFilename == "" && lockFilename == "" {
+		return preset
+	}
+	return &filenamesPreset{Preset: preset, spec: specFilename, lock: lockFilename}
+}
+
+type filenamesPreset struct {
+	Preset
+	spec string
+	lock string
+}
+
+func (p *filenamesPreset) GetSpecFilename() string {
+	if p.spec == "" {
+		return p.Preset.GetSpecFilename()
+	}
+	return p.spec
+}
+
+func (p *filenamesPreset) GetSpecLockFilename() string {
+	if p.lock == "" {
+		return p.Preset.GetSpecLockFilename()
+	}
+	return p.lock
+}
+
+// unwrapPreset returns the preset wrapped by WithFilenames, if any, so the
+// optional interfaces it implements can be found.
+func unwrapPreset(preset Preset) Preset {
+	if p, ok := preset.(*filenamesPreset); ok {
+		return unwrapPreset(p.Preset)
+	}
+	return preset
+}
+
 // DefaultPreset provides the default configuration for the vendor library.
 type DefaultPreset struct{}
 
@@ -70,6 +138,14 @@ func (dp *DefaultPreset) ForceFilters() bool {
 	return false
 }
 
+func (dp *DefaultPreset) GetJobs() int {
+	return DefaultJobs
+}
+
+func (dp *DefaultPreset) GetJobsPerHost() int {
+	return 0
+}
+
 // GetCacheDir default implementation tries to return a path under the user
 // home dir. When this operation fails, it resorts back to a temporary dir..
 func (dp *DefaultPreset) GetCacheDir() string {
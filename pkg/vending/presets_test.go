@@ -33,3 +33,26 @@ func TestDefaultPreset_GetCacheDir_DefaultsToTempDir(t *testing.T) {
 
 	assert.Equal(t, "/tmp/.cache/vending", sut.GetCacheDir())
 }
+
+func TestConcurrency(t *testing.T) {
+	jobs, jobsPerHost := Concurrency(&DefaultPreset{})
+	assert.Equal(t, DefaultJobs, jobs)
+	assert.Equal(t, 0, jobsPerHost)
+
+	jobs, jobsPerHost = Concurrency(testPreset)
+	assert.Equal(t, DefaultJobs, jobs)
+	assert.Equal(t, 0, jobsPerHost)
+}
+
+func TestWithFilenames(t *testing.T) {
+	sut := WithFilenames(testPreset, "some-dir/spec.yml", "")
+
+	assert.Equal(t, "some-dir/spec.yml", sut.GetSpecFilename())
+	assert.Equal(t, testPreset.GetSpecLockFilename(), sut.GetSpecLockFilename())
+	assert.Equal(t, testPreset.GetVendorDir(), sut.GetVendorDir())
+	assert.Equal(t, testPreset.GetPresetName(), sut.GetPresetName())
+	assert.Same(t, testPreset, WithFilenames(testPreset, "", ""))
+
+	jobs, _ := Concurrency(WithFilenames(&DefaultPreset{}, "", "some-dir/lock.yml"))
+	assert.Equal(t, DefaultJobs, jobs)
+}
@@ -0,0 +1,338 @@
+package vending
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Semver is a semantic version, as described in https://semver.org. A leading
+// "v" is accepted, and build metadata is ignored.
+type Semver struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// Constraint is a set of comparators that a Semver has to satisfy. It supports
+// the usual operators: "^1.4", "~2.0.3", ">=1.2.0 <2.0.0", "=1.0.0" and "*".
+// Comparators are separated by spaces or commas, and all of them must hold. An
+// operator can be followed by spaces, as in ">= 1.2.0".
+type Constraint struct {
+	raw         string
+	comparators []comparator
+	prereleases []Semver
+}
+
+type comparator struct {
+	op      string
+	version Semver
+}
+
+// ParseSemver parses a semantic version. Missing minor or patch numbers
+// default to zero, so "v1.4" is parsed as "1.4.0".
+func ParseSemver(s string) (Semver, error) {
+	v, _, err := parseSemver(s)
+	return v, err
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is lower, equal or greater
+// than other.
+func (v Semver) Compare(other Semver) int {
+	if c := compareInt(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+// LessThan returns whether v is lower than other.
+func (v Semver) LessThan(other Semver) bool {
+	return v.Compare(other) < 0
+}
+
+func (v Semver) String() string {
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// IsConstraint returns whether the reference looks like a semver constraint
+// rather than the name of a branch, a tag, or a commit.
+func IsConstraint(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	return ref == "*" || strings.ContainsAny(ref, " ,") ||
+		strings.HasPrefix(ref, "^") || strings.HasPrefix(ref, "~") ||
+		strings.HasPrefix(ref, ">") || strings.HasPrefix(ref, "<") ||
+		strings.HasPrefix(ref, "=")
+}
+
+// ParseConstraint parses a semver constraint.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: s}
+
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty constraint")
+	}
+
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		// An operator can be separated from its version, as in ">= 1.2.0".
+		if slices.Contains(operators, field) && i+1 < len(fields) {
+			i++
+			field += fields[i]
+		}
+
+		comparators, v, err := parseComparator(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %q: %w", s, err)
+		}
+		if v.Prerelease != "" {
+			c.prereleases = append(c.prereleases, v)
+		}
+		c.comparators = append(c.comparators, comparators...)
+	}
+	return c, nil
+}
+
+// Check returns whether the version satisfies the constraint. Prerelease
+// versions are only considered when a comparator of the constraint has a
+// prerelease of the same version: ">=1.0.0-rc.1" allows "1.0.0-rc.2", but not
+// "1.1.0-rc.1".
+func (c *Constraint) Check(v Semver) bool {
+	if v.Prerelease != "" && !c.allowsPrerelease(v) {
+		return false
+	}
+	for _, cmp := range c.comparators {
+		if !cmp.check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Latest returns the highest tag that satisfies the constraint. Tags that are
+// not semantic versions are ignored.
+func (c *Constraint) Latest(tags []string) (string, bool) {
+	type candidate struct {
+		tag     string
+		version Semver
+	}
+
+	candidates := []candidate{}
+	for _, tag := range tags {
+		v, err := ParseSemver(tag)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+		candidates = append(candidates, candidate{tag, v})
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].version.LessThan(candidates[j].version)
+	})
+	return candidates[len(candidates)-1].tag, true
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+func (c *Constraint) allowsPrerelease(v Semver) bool {
+	for _, p := range c.prereleases {
+		if p.Major == v.Major && p.Minor == v.Minor && p.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func (cmp comparator) check(v Semver) bool {
+	r := v.Compare(cmp.version)
+	switch cmp.op {
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	default:
+		return r == 0
+	}
+}
+
+// operators are the prefixes of a comparator, longest first.
+var operators = []string{">=", "<=", ">", "<", "=", "^", "~"}
+
+// parseComparator returns the comparators of s, and the version as written.
+func parseComparator(s string) ([]comparator, Semver, error) {
+	if s == "*" {
+		return []comparator{{">=", Semver{}}}, Semver{}, nil
+	}
+
+	for _, op := range operators {
+		if !strings.HasPrefix(s, op) {
+			continue
+		}
+
+		v, parts, err := parseSemver(s[len(op):])
+		if err != nil {
+			return nil, Semver{}, err
+		}
+
+		switch op {
+		case "^":
+			return []comparator{{">=", v}, {"<", caretUpperBound(v, parts)}}, v, nil
+		case "~":
+			return []comparator{{">=", v}, {"<", tildeUpperBound(v, parts)}}, v, nil
+		default:
+			return []comparator{{op, v}}, v, nil
+		}
+	}
+
+	v, _, err := parseSemver(s)
+	if err != nil {
+		return nil, Semver{}, err
+	}
+	return []comparator{{"=", v}}, v, nil
+}
+
+// caretUpperBound allows changes that do not modify the left-most non-zero
+// number: ^1.4 := <2.0.0, ^0.4 := <0.5.0, ^0.0.3 := <0.0.4.
+func caretUpperBound(v Semver, parts int) Semver {
+	switch {
+	case v.Major > 0 || parts == 1:
+		return Semver{Major: v.Major + 1, Prerelease: "0"}
+	case v.Minor > 0 || parts == 2:
+		return Semver{Minor: v.Minor + 1, Prerelease: "0"}
+	default:
+		return Semver{Patch: v.Patch + 1, Prerelease: "0"}
+	}
+}
+
+// tildeUpperBound allows patch level changes when a minor version is given,
+// and minor level changes otherwise: ~2.0.3 := <2.1.0, ~2 := <3.0.0.
+func tildeUpperBound(v Semver, parts int) Semver {
+	if parts == 1 {
+		return Semver{Major: v.Major + 1, Prerelease: "0"}
+	}
+	return Semver{Major: v.Major, Minor: v.Minor + 1, Prerelease: "0"}
+}
+
+func parseSemver(s string) (Semver, int, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	v := Semver{}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+		if v.Prerelease == "" {
+			return Semver{}, 0, fmt.Errorf("invalid version %q: empty prerelease", raw)
+		}
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Semver{}, 0, fmt.Errorf("invalid version %q: too many numbers", raw)
+	}
+
+	numbers := [3]int{}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Semver{}, 0, fmt.Errorf("invalid version %q", raw)
+		}
+		numbers[i] = n
+	}
+
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+	return v, len(parts), nil
+}
+
+// compareVersions compares two version strings, versions that cannot be
+// parsed, like an empty one, are considered older than any valid version.
+func compareVersions(a, b string) int {
+	va, errA := ParseSemver(a)
+	vb, errB := ParseSemver(b)
+	switch {
+	case errA != nil && errB != nil:
+		return 0
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	default:
+		return va.Compare(vb)
+	}
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrerelease follows the precedence rules of semver: a version without
+// prerelease is greater, numeric identifiers are compared numerically and
+// have lower precedence than alphanumeric ones.
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := compareInt(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInt(len(as), len(bs))
+}
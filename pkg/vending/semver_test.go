@@ -0,0 +1,126 @@
+package vending
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSemver(t *testing.T) {
+	v, err := ParseSemver("v1.4.2-rc.1+build.5")
+	assert.NoError(t, err)
+	assert.Equal(t, Semver{1, 4, 2, "rc.1"}, v)
+
+	v, err = ParseSemver("1.4")
+	assert.NoError(t, err)
+	assert.Equal(t, Semver{1, 4, 0, ""}, v)
+
+	for _, invalid := range []string{"", "master", "v1.2.3.4", "1.x", "1.0.0-"} {
+		_, err = ParseSemver(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSemver_Compare(t *testing.T) {
+	ordered := []string{
+		"v0.5.1",
+		"v0.10.0",
+		"v1.0.0-alpha",
+		"v1.0.0-alpha.1",
+		"v1.0.0-alpha.beta",
+		"v1.0.0-beta.2",
+		"v1.0.0-beta.11",
+		"v1.0.0",
+		"v1.10.0",
+	}
+
+	for i := 0; i < len(ordered)-1; i++ {
+		a, _ := ParseSemver(ordered[i])
+		b, _ := ParseSemver(ordered[i+1])
+		assert.True(t, a.LessThan(b), "%s < %s", a, b)
+		assert.Equal(t, 1, b.Compare(a))
+		assert.Equal(t, 0, a.Compare(a))
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 1, compareVersions("v0.10.0", "v0.5.1"))
+	assert.Equal(t, 1, compareVersions("v0.5.10", "v0.5.9"))
+	assert.Equal(t, -1, compareVersions("v0.5.1", "v0.10.0"))
+	assert.Equal(t, 0, compareVersions("v0.5.1", "0.5.1"))
+	assert.Equal(t, -1, compareVersions("", "v0.0.1"))
+	assert.Equal(t, 1, compareVersions("v0.0.1", "not-a-version"))
+}
+
+func TestIsConstraint(t *testing.T) {
+	for _, ref := range []string{"^1.4", "~2.0.3", ">=1.0.0 <2.0.0", "=1.0.0", "*"} {
+		assert.True(t, IsConstraint(ref), ref)
+	}
+	for _, ref := range []string{"master", "v1.4.0", "04abf50e", "feature/x"} {
+		assert.False(t, IsConstraint(ref), ref)
+	}
+}
+
+func TestConstraint_Latest(t *testing.T) {
+	tags := []string{
+		"v0.4.0", "v0.4.9", "v0.5.0",
+		"v1.3.0", "v1.4.0", "v1.4.7", "v1.9.1", "v2.0.0-rc.1",
+		"2.0.3", "2.0.9", "2.1.0",
+		"not-a-version",
+	}
+
+	cases := []struct {
+		constraint, expected string
+	}{
+		{"^1.4", "v1.9.1"},
+		{"^0.4", "v0.4.9"},
+		{"~1.4", "v1.4.7"},
+		{"~2.0.3", "2.0.9"},
+		{"~2", "2.1.0"},
+		{">=1.0.0 <1.4.5", "v1.4.0"},
+		{">=1.0.0, <=1.3.0", "v1.3.0"},
+		{"=1.4.0", "v1.4.0"},
+		{"*", "2.1.0"},
+		{">=2.0.0-rc.1 <2.0.3", "v2.0.0-rc.1"},
+		{">= 1.0.0 < 1.4.5", "v1.4.0"},
+		{"^ 1.4", "v1.9.1"},
+	}
+
+	for _, c := range cases {
+		constraint, err := ParseConstraint(c.constraint)
+		assert.NoError(t, err, c.constraint)
+
+		actual, ok := constraint.Latest(tags)
+		assert.True(t, ok, c.constraint)
+		assert.Equal(t, c.expected, actual, c.constraint)
+	}
+}
+
+func TestConstraint_Check_Prerelease(t *testing.T) {
+	constraint, err := ParseConstraint(">=1.0.0-rc.1")
+	assert.NoError(t, err)
+
+	for _, allowed := range []string{"1.0.0-rc.1", "1.0.0-rc.2", "1.0.0", "1.1.0"} {
+		v, _ := ParseSemver(allowed)
+		assert.True(t, constraint.Check(v), allowed)
+	}
+	for _, denied := range []string{"1.0.0-alpha", "1.1.0-rc.1", "2.0.0-rc.1"} {
+		v, _ := ParseSemver(denied)
+		assert.False(t, constraint.Check(v), denied)
+	}
+}
+
+func TestConstraint_Latest_WhenNoTagMatches(t *testing.T) {
+	constraint, err := ParseConstraint("^3.0")
+	assert.NoError(t, err)
+
+	_, ok := constraint.Latest([]string{"v1.0.0", "v2.0.0"})
+	assert.False(t, ok)
+}
+
+func TestParseConstraint_Invalid(t *testing.T) {
+	for _, invalid := range []string{"", "^", "^x.y", ">=1.0.0 <two"} {
+		_, err := ParseConstraint(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -2,8 +2,10 @@ package vending
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
+	"slices"
 	"strings"
 
 	"github.com/alevinval/vendor-go/pkg/log"
@@ -25,6 +27,7 @@ type Spec struct {
 	Filters    *Filters      `yaml:",inline"`
 	Deps       []*Dependency `yaml:"deps"`
 	preset     Preset        `yaml:"-"`
+	fs         FS            `yaml:"-"`
 }
 
 // NewSpec allocates a new Spec instance with the default initialization.
@@ -34,14 +37,22 @@ func NewSpec(preset Preset) *Spec {
 		Version: VERSION,
 		Filters: NewFilters(),
 		Deps:    []*Dependency{},
+		fs:      DirFS(""),
 	}
 	spec.applyPreset(preset)
 	return spec
 }
 
+// WithFS configures the filesystem where the spec file is read and written,
+// instead of the working directory.
+func (s *Spec) WithFS(fsys FS) *Spec {
+	s.fs = fsys
+	return s
+}
+
 // AddDependency adds a Dependency to the list of dependencies to vendor.
 func (s *Spec) AddDependency(dependency *Dependency) {
-	if dep, ok := s.findDep(dependency); ok {
+	if dep, ok := s.findDep(dependency.ID()); ok {
 		dep.Update(dependency)
 	} else {
 		s.Deps = append(s.Deps, dependency)
@@ -49,40 +60,105 @@ func (s *Spec) AddDependency(dependency *Dependency) {
 	s.applyPreset(s.preset)
 }
 
+// RemoveDependency removes the Dependency found by FindDependency, and
+// returns it.
+func (s *Spec) RemoveDependency(id string) (*Dependency, bool) {
+	dep, ok := s.FindDependency(id)
+	if !ok {
+		return nil, false
+	}
+	s.Deps = slices.DeleteFunc(s.Deps, func(d *Dependency) bool {
+		return d == dep
+	})
+	return dep, true
+}
+
+// FiltersFor returns the effective Filters of a dependency, this is the union
+// of the Filters of the spec and the ones of the dependency.
+func (s *Spec) FiltersFor(dep *Dependency) *Filters {
+	return s.Filters.Clone().ApplyFilters(dep.Filters)
+}
+
+// FiltersDigest returns a digest of everything that decides which files of a
+// dependency are vendored, and where: its effective Filters, Dest, Strip and
+// Priority.
+func (s *Spec) FiltersDigest(dep *Dependency) string {
+	filters := s.FiltersFor(dep)
+
+	w, sum := NewDigest()
+	fmt.Fprintf(w, "extensions\x00%s\n", strings.Join(filters.Extensions, "\x00"))
+	fmt.Fprintf(w, "targets\x00%s\n", strings.Join(filters.Targets, "\x00"))
+	fmt.Fprintf(w, "ignores\x00%s\n", strings.Join(filters.Ignores, "\x00"))
+	fmt.Fprintf(w, "dest\x00%s\n", dep.Dest)
+	fmt.Fprintf(w, "strip\x00%s\n", dep.Strip)
+	// Omitted when not set, so the digests of older locks stay the same.
+	if dep.Priority != 0 {
+		fmt.Fprintf(w, "priority\x00%d\n", dep.Priority)
+	}
+	if filters.ExportIgnore {
+		fmt.Fprintf(w, "export_ignore\n")
+	}
+	if filters.VendorIgnore {
+		fmt.Fprintf(w, "vendor_ignore\n")
+	}
+	return sum()
+}
+
 // Load Spec from the filesystem.
 func (s *Spec) Load() error {
+	_, err := s.LoadAndMigrate()
+	return err
+}
+
+// LoadAndMigrate loads the Spec from the filesystem, and reports the schema
+// migrations that were applied when it was produced by an older version of
+// the tool.
+func (s *Spec) LoadAndMigrate() (*MigrationReport, error) {
 	preset := s.preset
 
 	filename := preset.GetSpecFilename()
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return fmt.Errorf("cannot read file: %w", err)
+	data, err := s.fs.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %w", ErrSpecNotFound, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read file: %w", err)
 	}
 
-	err = yaml.Unmarshal(data, s)
+	report, err := unmarshalAndMigrate(data, s, specMigration)
 	if err != nil {
-		return fmt.Errorf("cannot unmarshal: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrSpecInvalid, err)
 	}
 
 	s.applyPreset(preset)
-	return nil
+	return report, nil
 }
 
 // Save converts the Spec to YAML, and writes the data in the spec file,
-// as specified by the Preset.
+// as specified by the Preset. The file is replaced atomically.
 func (s *Spec) Save() error {
-	filename := s.preset.GetSpecFilename()
-	data, err := toYaml(s)
+	data, err := s.Marshal()
 	if err != nil {
-		return fmt.Errorf("cannot convert to yaml: %w", err)
+		return err
 	}
-	err = os.WriteFile(filename, data, os.ModePerm)
+	err = s.fs.WriteFile(s.preset.GetSpecFilename(), data)
 	if err != nil {
 		return fmt.Errorf("cannot write file: %w", err)
 	}
 	return nil
 }
 
+// Marshal converts the Spec to YAML. When the spec file already exists, only
+// the parts that changed are rewritten, so comments and formatting are
+// preserved.
+func (s *Spec) Marshal() ([]byte, error) {
+	existing, _ := s.fs.ReadFile(s.preset.GetSpecFilename())
+	data, err := mergeYaml(existing, s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert to yaml: %w", err)
+	}
+	return data, nil
+}
+
 func (s *Spec) applyPreset(preset Preset) {
 	s.preset = preset
 	if s.VendorDir == "" {
@@ -90,9 +166,9 @@ func (s *Spec) applyPreset(preset Preset) {
 	}
 	s.PresetName = preset.GetPresetName()
 
-	if s.Version < VERSION {
+	if c := compareVersions(s.Version, VERSION); c < 0 {
 		s.Version = VERSION
-	} else if s.Version > VERSION {
+	} else if c > 0 {
 		log.S().Warnf("%s this project uses vending@%s (currently installed vending@%s)",
 			color.YellowString("[WARNING]"), color.GreenString(s.Version), color.RedString(VERSION))
 	}
@@ -103,9 +179,29 @@ func (s *Spec) applyPreset(preset Preset) {
 	}
 }
 
-func (s *Spec) findDep(dependency *Dependency) (*Dependency, bool) {
+// FindDependency finds a Dependency by its identity, see Dependency.ID. When
+// no dependency has that identity, the id is matched against the URLs, as long
+// as it is not ambiguous.
+func (s *Spec) FindDependency(id string) (*Dependency, bool) {
+	if dep, ok := s.findDep(id); ok {
+		return dep, true
+	}
+
+	var found *Dependency
+	for _, dep := range s.Deps {
+		if strings.EqualFold(dep.URL, id) {
+			if found != nil {
+				return nil, false
+			}
+			found = dep
+		}
+	}
+	return found, found != nil
+}
+
+func (s *Spec) findDep(id string) (*Dependency, bool) {
 	for _, dep := range s.Deps {
-		if strings.EqualFold(dep.URL, dependency.URL) {
+		if strings.EqualFold(dep.ID(), id) {
 			return dep, true
 		}
 	}
@@ -121,7 +217,7 @@ func checkPreset(preset Preset, warn bool) Preset {
 	}
 
 	if warn {
-		switch p := preset.(type) {
+		switch p := unwrapPreset(preset).(type) {
 		case *DefaultPreset:
 			break
 		default:
@@ -134,6 +230,27 @@ func checkPreset(preset Preset, warn bool) Preset {
 	return preset
 }
 
+// unmarshalAndMigrate decodes the YAML data into obj, after running the
+// pending migrations over the document.
+func unmarshalAndMigrate(data []byte, obj interface{}, selectFn migrationFunc) (*MigrationReport, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal: %w", err)
+	}
+
+	report, err := applyMigrations(doc, selectFn)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) > 0 {
+		if err := doc.Decode(obj); err != nil {
+			return nil, fmt.Errorf("cannot unmarshal: %w", err)
+		}
+	}
+	return report, nil
+}
+
 func toYaml(obj interface{}) ([]byte, error) {
 	b := &bytes.Buffer{}
 	encoder := yaml.NewEncoder(b)
@@ -2,10 +2,8 @@ package vending
 
 import (
 	"fmt"
-	"os"
+	"slices"
 	"strings"
-
-	"gopkg.in/yaml.v3"
 )
 
 // SpecLock holds relevant information related to the specification of what
@@ -16,6 +14,7 @@ type SpecLock struct {
 	Version string            `yaml:"version"`
 	Deps    []*DependencyLock `yaml:"deps"`
 	preset  Preset            `yaml:"-"`
+	fs      FS                `yaml:"-"`
 }
 
 // NewSpecLock allocates a new SpecLock instance with a default initialization.
@@ -25,31 +24,106 @@ func NewSpecLock(preset Preset) *SpecLock {
 		Version: VERSION,
 		Deps:    []*DependencyLock{},
 		preset:  preset,
+		fs:      DirFS(""),
 	}
 }
 
+// WithFS configures the filesystem where the lock file is read and written,
+// instead of the working directory.
+func (s *SpecLock) WithFS(fsys FS) *SpecLock {
+	s.fs = fsys
+	return s
+}
+
 // AddDependencyLock adds a DependencyLock to the list of locked dependencies.
 func (s *SpecLock) AddDependencyLock(lock *DependencyLock) {
-	existing, ok := s.FindByURL(lock.URL)
+	existing, ok := s.FindByID(lock.ID())
 	if ok {
-		existing.Commit = lock.Commit
+		*existing = *lock
 	} else {
 		s.Deps = append(s.Deps, lock)
 	}
 }
 
-func (s *SpecLock) Prune(spec *Spec) {
-	pruned := []*DependencyLock{}
+// RemoveByID removes the DependencyLock with the given identity, and returns
+// it.
+func (s *SpecLock) RemoveByID(id string) (*DependencyLock, bool) {
+	lock, ok := s.FindByID(id)
+	if !ok {
+		return nil, false
+	}
+	s.Deps = slices.DeleteFunc(s.Deps, func(d *DependencyLock) bool {
+		return d == lock
+	})
+	return lock, true
+}
+
+// Prune removes the locks of the dependencies that are no longer in the spec,
+// and returns them.
+func (s *SpecLock) Prune(spec *Spec) []*DependencyLock {
+	kept := []*DependencyLock{}
 	for _, dep := range spec.Deps {
-		lockedDep, found := s.FindByURL(dep.URL)
+		lockedDep, found := s.FindByID(dep.ID())
 		if found {
-			pruned = append(pruned, lockedDep)
+			kept = append(kept, lockedDep)
+		}
+	}
+
+	removed := []*DependencyLock{}
+	for _, lock := range s.Deps {
+		if !slices.Contains(kept, lock) {
+			removed = append(removed, lock)
+		}
+	}
+	s.Deps = kept
+	return removed
+}
+
+// Diff compares the locked dependencies with the spec, and describes every
+// difference: dependencies that are not locked, locked dependencies that are
+// no longer in the spec, and dependencies that were locked from different
+// url, branch, ref or filters. It returns an empty list when the lock is in sync.
+func (s *SpecLock) Diff(spec *Spec) []string {
+	diff := []string{}
+	for _, dep := range spec.Deps {
+		lock, ok := s.FindByID(dep.ID())
+		if !ok {
+			diff = append(diff, fmt.Sprintf("+ %s: not locked", dep.ID()))
+			continue
+		}
+		if !strings.EqualFold(lock.URL, dep.URL) {
+			diff = append(diff, fmt.Sprintf("~ %s: url %q is locked as %q", dep.ID(), dep.URL, lock.URL))
+		}
+		if lock.Branch != dep.Branch {
+			diff = append(diff, fmt.Sprintf("~ %s: branch %q is locked as %q", dep.ID(), dep.Branch, lock.Branch))
+		}
+		if lock.Ref != dep.Ref {
+			diff = append(diff, fmt.Sprintf("~ %s: ref %q is locked as %q", dep.ID(), dep.Ref, lock.Ref))
+		}
+		if lock.FiltersDigest != spec.FiltersDigest(dep) {
+			diff = append(diff, fmt.Sprintf("~ %s: filters changed since it was locked", dep.ID()))
 		}
 	}
-	s.Deps = pruned
+	for _, lock := range s.Deps {
+		if _, ok := spec.findDep(lock.ID()); !ok {
+			diff = append(diff, fmt.Sprintf("- %s: locked, but not in the spec", lock.ID()))
+		}
+	}
+	return diff
+}
+
+// FindByID finds a DependencyLock by its identity, the name when the
+// dependency is named, or the URL otherwise.
+func (s *SpecLock) FindByID(id string) (*DependencyLock, bool) {
+	for _, dep := range s.Deps {
+		if strings.EqualFold(dep.ID(), id) {
+			return dep, true
+		}
+	}
+	return nil, false
 }
 
-// FindByURL finds a DependencyLock by URL.
+// FindByURL finds the first DependencyLock with the given URL.
 func (s *SpecLock) FindByURL(url string) (*DependencyLock, bool) {
 	for _, dep := range s.Deps {
 		if strings.EqualFold(dep.URL, url) {
@@ -61,33 +135,40 @@ func (s *SpecLock) FindByURL(url string) (*DependencyLock, bool) {
 
 // Load SpecLock from the filesystem.
 func (s *SpecLock) Load() error {
+	_, err := s.LoadAndMigrate()
+	return err
+}
+
+// LoadAndMigrate loads the SpecLock from the filesystem, and reports the
+// schema migrations that were applied when it was produced by an older version
+// of the tool. A missing lock file is not an error, and reports no changes.
+func (s *SpecLock) LoadAndMigrate() (*MigrationReport, error) {
 	preset := s.preset
 
 	filename := preset.GetSpecLockFilename()
-	data, err := os.ReadFile(filename)
+	data, err := s.fs.ReadFile(filename)
 	if err != nil {
-		return nil
+		return &MigrationReport{From: s.Version, To: s.Version}, nil
 	}
 
-	err = yaml.Unmarshal(data, s)
+	report, err := unmarshalAndMigrate(data, s, lockMigration)
 	if err != nil {
-		return fmt.Errorf("cannot unmarshal: %w", err)
+		return nil, err
 	}
 
 	s.applyPreset(preset)
-	return nil
+	return report, nil
 }
 
 // Save converts the SpecLock to YAML, and writes the data in the spec lock
-// file, as specified by the Preset.
+// file, as specified by the Preset. The file is replaced atomically.
 func (s *SpecLock) Save() error {
-	filename := s.preset.GetSpecLockFilename()
-	data, err := toYaml(s)
+	data, err := s.Marshal()
 	if err != nil {
-		return fmt.Errorf("cannot convert to yaml:  %w", err)
+		return err
 	}
 
-	err = os.WriteFile(filename, data, os.ModePerm)
+	err = s.fs.WriteFile(s.preset.GetSpecLockFilename(), data)
 	if err != nil {
 		return fmt.Errorf("cannot write file: %w", err)
 	}
@@ -95,10 +176,19 @@ func (s *SpecLock) Save() error {
 	return nil
 }
 
+// Marshal converts the SpecLock to YAML.
+func (s *SpecLock) Marshal() ([]byte, error) {
+	data, err := toYaml(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert to yaml:  %w", err)
+	}
+	return data, nil
+}
+
 func (s *SpecLock) applyPreset(preset Preset) {
 	s.preset = preset
 
-	if s.Version < VERSION {
+	if compareVersions(s.Version, VERSION) < 0 {
 		s.Version = VERSION
 	}
 }
@@ -2,7 +2,6 @@ package vending
 
 import (
 	"fmt"
-	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -50,14 +49,15 @@ func TestSpecLock_DoesNotBumpVersion_WhenSpecIsNewer(t *testing.T) {
 }
 
 func TestSpecLock_SaveAndLoad(t *testing.T) {
+	fsys := newTestFS(t)
 	depLock := NewDependencyLock("some-url", "some-commit")
-	expected := NewSpecLock(testPreset)
+	expected := NewSpecLock(testPreset).WithFS(fsys)
 	expected.AddDependencyLock(depLock)
 
 	err := expected.Save()
 	assert.NoError(t, err)
 
-	actual := NewSpecLock(testPreset)
+	actual := NewSpecLock(testPreset).WithFS(fsys)
 	assert.NotEqual(t, expected, actual)
 
 	actual.Load()
@@ -65,8 +65,9 @@ func TestSpecLock_SaveAndLoad(t *testing.T) {
 }
 
 func TestSpecLock_SaveOutput(t *testing.T) {
+	fsys := newTestFS(t)
 	depLock := NewDependencyLock("some-url", "some-commit")
-	sut := NewSpecLock(testPreset)
+	sut := NewSpecLock(testPreset).WithFS(fsys)
 	sut.AddDependencyLock(depLock)
 
 	err := sut.Save()
@@ -78,7 +79,122 @@ deps:
     commit: some-commit
 `, VERSION)
 
-	actual, err := os.ReadFile(testPreset.GetSpecLockFilename())
+	actual, err := fsys.ReadFile(testPreset.GetSpecLockFilename())
 	assert.NoError(t, err)
 	assert.Equal(t, expected, string(actual))
 }
+
+func TestSpecLockAddUpdates_Tag(t *testing.T) {
+	sut := NewSpecLock(nil)
+	sut.AddDependencyLock(NewDependencyLock("some-url", "some-commit"))
+
+	other := NewDependencyLock("some-url", "other-commit")
+	other.Tag = "v1.4.0"
+	sut.AddDependencyLock(other)
+
+	assert.Equal(t, "v1.4.0", sut.Deps[0].Tag)
+}
+
+func TestSpecLockAdd_SameURLWithDifferentNames(t *testing.T) {
+	sut := NewSpecLock(nil)
+
+	one := NewDependencyLock("some-url", "one-commit")
+	one.Name = "one"
+	two := NewDependencyLock("some-url", "two-commit")
+	two.Name = "two"
+	sut.AddDependencyLock(one)
+	sut.AddDependencyLock(two)
+
+	assert.Equal(t, []*DependencyLock{one, two}, sut.Deps)
+
+	actual, ok := sut.FindByID("two")
+	assert.True(t, ok)
+	assert.Equal(t, "two-commit", actual.Commit)
+}
+
+func TestSpecLockPrune_MatchesByID(t *testing.T) {
+	spec := NewSpec(nil)
+	named := NewDependency("some-url", "some-branch")
+	named.Name = "named"
+	spec.AddDependency(named)
+
+	sut := NewSpecLock(nil)
+	kept := NewDependencyLock("some-url", "some-commit")
+	kept.Name = "named"
+	sut.AddDependencyLock(kept)
+	removed := NewDependencyLock("some-url", "other-commit")
+	sut.AddDependencyLock(removed)
+
+	actual := sut.Prune(spec)
+
+	assert.Equal(t, []*DependencyLock{kept}, sut.Deps)
+	assert.Equal(t, []*DependencyLock{removed}, actual)
+}
+
+func TestSpecLockRemoveByID(t *testing.T) {
+	sut := NewSpecLock(nil)
+	kept := NewDependencyLock("some-url", "some-commit")
+	removed := NewDependencyLock("other-url", "other-commit")
+	sut.AddDependencyLock(kept)
+	sut.AddDependencyLock(removed)
+
+	actual, ok := sut.RemoveByID("OTHER-URL")
+	assert.True(t, ok)
+	assert.Equal(t, removed, actual)
+	assert.Equal(t, []*DependencyLock{kept}, sut.Deps)
+
+	_, ok = sut.RemoveByID("other-url")
+	assert.False(t, ok)
+}
+
+func TestSpecLockDiff_WhenInSync_IsEmpty(t *testing.T) {
+	spec := NewSpec(nil)
+	dep := NewDependency("some-url", "some-branch")
+	spec.AddDependency(dep)
+
+	sut := NewSpecLock(nil)
+	sut.AddDependencyLock(lockFor(spec, dep))
+
+	assert.Empty(t, sut.Diff(spec))
+}
+
+func TestSpecLockDiff_ReportsDifferences(t *testing.T) {
+	spec := NewSpec(nil)
+	changed := NewDependency("changed-url", "some-branch")
+	missing := NewDependency("missing-url", "some-branch")
+	spec.AddDependency(changed)
+	spec.AddDependency(missing)
+
+	renamed := NewDependency("old-url", "some-branch")
+	renamed.Name = "renamed"
+	spec.AddDependency(renamed)
+
+	sut := NewSpecLock(nil)
+	sut.AddDependencyLock(lockFor(spec, changed))
+	sut.AddDependencyLock(lockFor(spec, renamed))
+	sut.AddDependencyLock(NewDependencyLock("extra-url", "some-commit"))
+
+	renamed.URL = "new-url"
+
+	changed.Branch = "other-branch"
+	changed.Ref = "^1.4"
+	changed.Filters.AddTarget("some-target")
+
+	assert.Equal(t, []string{
+		`~ changed-url: branch "other-branch" is locked as "some-branch"`,
+		`~ changed-url: ref "^1.4" is locked as ""`,
+		"~ changed-url: filters changed since it was locked",
+		"+ missing-url: not locked",
+		`~ renamed: url "new-url" is locked as "old-url"`,
+		"- extra-url: locked, but not in the spec",
+	}, sut.Diff(spec))
+}
+
+func lockFor(spec *Spec, dep *Dependency) *DependencyLock {
+	lock := NewDependencyLock(dep.URL, "some-commit")
+	lock.Name = dep.Name
+	lock.Branch = dep.Branch
+	lock.Ref = dep.Ref
+	lock.FiltersDigest = spec.FiltersDigest(dep)
+	return lock
+}
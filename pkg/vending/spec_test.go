@@ -2,7 +2,6 @@ package vending
 
 import (
 	"fmt"
-	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -57,11 +56,10 @@ func (tp *TestPreset) GetFiltersForDependency(dep *Dependency) *Filters {
 		AddIgnore(ignore)
 }
 
-func (tp *TestPreset) cleanUp() {
-	os.RemoveAll(tp.GetCacheDir())
-	os.RemoveAll(tp.GetVendorDir())
-	os.RemoveAll(tp.GetSpecFilename())
-	os.RemoveAll(tp.GetSpecLockFilename())
+// newTestFS returns a DirFS rooted at a temporary directory, so tests do not
+// write into the package directory.
+func newTestFS(t *testing.T) DirFS {
+	return DirFS(t.TempDir())
 }
 
 func TestNewSpec_LoadsPreset(t *testing.T) {
@@ -116,6 +114,95 @@ func TestSpecAdd_WhenDepAlreadyPresent_UpdatesExistingDep(t *testing.T) {
 	assert.Equal(t, other.Filters, sut.Deps[0].Filters)
 }
 
+func TestSpecAdd_SameURLWithDifferentNames_AddsBoth(t *testing.T) {
+	sut := NewSpec(nil)
+
+	one := NewDependency("some-url", "one-branch")
+	one.Name = "one"
+	two := NewDependency("some-url", "two-branch")
+	two.Name = "two"
+	sut.AddDependency(one)
+	sut.AddDependency(two)
+
+	assert.Equal(t, []*Dependency{one, two}, sut.Deps)
+}
+
+func TestSpecFindDependency(t *testing.T) {
+	sut := NewSpec(nil)
+
+	unnamed := NewDependency("unnamed-url", "some-branch")
+	one := NewDependency("shared-url", "one-branch")
+	one.Name = "one"
+	two := NewDependency("shared-url", "two-branch")
+	two.Name = "two"
+	sut.AddDependency(unnamed)
+	sut.AddDependency(one)
+	sut.AddDependency(two)
+
+	actual, ok := sut.FindDependency("UNNAMED-URL")
+	assert.True(t, ok)
+	assert.Equal(t, unnamed, actual)
+
+	actual, ok = sut.FindDependency("two")
+	assert.True(t, ok)
+	assert.Equal(t, two, actual)
+
+	_, ok = sut.FindDependency("shared-url")
+	assert.False(t, ok, "ambiguous url should not match")
+
+	_, ok = sut.FindDependency("missing")
+	assert.False(t, ok)
+}
+
+func TestSpecRemoveDependency(t *testing.T) {
+	sut := NewSpec(nil)
+	one := NewDependency("one-url", "some-branch")
+	two := NewDependency("two-url", "some-branch")
+	two.Name = "two"
+	sut.AddDependency(one)
+	sut.AddDependency(two)
+
+	actual, ok := sut.RemoveDependency("two")
+	assert.True(t, ok)
+	assert.Equal(t, two, actual)
+	assert.Equal(t, []*Dependency{one}, sut.Deps)
+
+	_, ok = sut.RemoveDependency("two")
+	assert.False(t, ok)
+}
+
+func TestSpecFiltersDigest_ChangesWithInputs(t *testing.T) {
+	sut := NewSpec(nil)
+	dep := NewDependency("some-url", "some-branch")
+	sut.AddDependency(dep)
+
+	digests := map[string]bool{sut.FiltersDigest(dep): true}
+
+	dep.Filters.AddExtension("proto")
+	digests[sut.FiltersDigest(dep)] = true
+
+	sut.Filters.AddIgnore("docs")
+	digests[sut.FiltersDigest(dep)] = true
+
+	dep.Dest = "some-dest"
+	digests[sut.FiltersDigest(dep)] = true
+
+	dep.Strip = "some-strip"
+	digests[sut.FiltersDigest(dep)] = true
+
+	dep.Priority = 1
+	digests[sut.FiltersDigest(dep)] = true
+
+	sut.Filters.ExportIgnore = true
+	digests[sut.FiltersDigest(dep)] = true
+
+	dep.Filters.VendorIgnore = true
+	digests[sut.FiltersDigest(dep)] = true
+
+	assert.Len(t, digests, 8)
+	assert.Equal(t, sut.FiltersDigest(dep), sut.FiltersDigest(dep))
+}
+
 func TestSpec_WhenForceFilters_OverridesFilters(t *testing.T) {
 	preset := &TestPreset{true}
 
@@ -157,16 +244,16 @@ func TestSpec_DoesNotBumpVersion_WhenSpecIsNewer(t *testing.T) {
 }
 
 func TestSpec_SaveThenLoad(t *testing.T) {
-	defer testPreset.cleanUp()
+	fsys := newTestFS(t)
 
 	dep := NewDependency("some-url", "some-branch")
-	expected := NewSpec(testPreset)
+	expected := NewSpec(testPreset).WithFS(fsys)
 	expected.AddDependency(dep)
 
 	err := expected.Save()
 	assert.NoError(t, err)
 
-	actual := NewSpec(testPreset)
+	actual := NewSpec(testPreset).WithFS(fsys)
 	assert.NotEqual(t, expected, actual)
 
 	err = actual.Load()
@@ -175,10 +262,10 @@ func TestSpec_SaveThenLoad(t *testing.T) {
 }
 
 func TestSpec_SaveOutput(t *testing.T) {
-	defer testPreset.cleanUp()
+	fsys := newTestFS(t)
 
 	dep := NewDependency("some-url", "some-branch")
-	sut := NewSpec(&TestPreset{})
+	sut := NewSpec(&TestPreset{}).WithFS(fsys)
 	sut.AddDependency(dep)
 
 	err := sut.Save()
@@ -204,7 +291,105 @@ deps:
       - preset-ignore-for-some-url
 `, VERSION)
 
-	actual, err := os.ReadFile("some-spec-filename")
+	actual, err := fsys.ReadFile("some-spec-filename")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, string(actual))
+}
+
+func TestSpec_Save_PreservesCommentsAndFormatting(t *testing.T) {
+	fsys := newTestFS(t)
+
+	original := fmt.Sprintf(`# vendored protos, see docs/vendoring.md
+version: %s
+preset: test-preset
+vendor_dir: test-vendor-dir
+
+# shared by every dependency
+extensions:
+  - preset-extension
+targets:
+  - preset-target
+ignores:
+  - preset-ignore
+
+deps:
+  # needed by the ledger service
+  - url: "some-url"
+    branch: some-branch # keep in sync with the server
+    extensions:
+      - preset-extension-for-some-url
+    targets:
+      - preset-target-for-some-url
+    ignores:
+      - preset-ignore-for-some-url
+`, VERSION)
+	err := fsys.WriteFile(testPreset.GetSpecFilename(), []byte(original))
+	assert.NoError(t, err)
+
+	sut := NewSpec(testPreset).WithFS(fsys)
+	assert.NoError(t, sut.Load())
+
+	sut.AddDependency(NewDependency("other-url", "other-branch"))
+	assert.NoError(t, sut.Save())
+
+	expected := original + `  - url: other-url
+    branch: other-branch
+    extensions:
+      - preset-extension-for-other-url
+    targets:
+      - preset-target-for-other-url
+    ignores:
+      - preset-ignore-for-other-url
+`
+
+	actual, err := fsys.ReadFile(testPreset.GetSpecFilename())
 	assert.NoError(t, err)
 	assert.Equal(t, expected, string(actual))
 }
+
+func TestSpec_Save_UpdatesOnlyChangedNodes(t *testing.T) {
+	fsys := newTestFS(t)
+
+	original := fmt.Sprintf(`version: %s
+preset: test-preset
+vendor_dir: test-vendor-dir
+deps:
+  - url: some-url # the upstream
+    branch: some-branch
+`, VERSION)
+	err := fsys.WriteFile(testPreset.GetSpecFilename(), []byte(original))
+	assert.NoError(t, err)
+
+	sut := NewSpec(testPreset).WithFS(fsys)
+	assert.NoError(t, sut.Load())
+
+	sut.Deps[0].Branch = "other-branch"
+	sut.Deps[0].Ref = "^1.4"
+	assert.NoError(t, sut.Save())
+
+	actual, err := fsys.ReadFile(testPreset.GetSpecFilename())
+	assert.NoError(t, err)
+	assert.Contains(t, string(actual), `deps:
+  - url: some-url # the upstream
+    branch: other-branch
+    ref: ^1.4
+    extensions:`)
+}
+
+func TestSpecLoad_NotFound(t *testing.T) {
+	sut := NewSpec(testPreset).WithFS(newTestFS(t))
+
+	err := sut.Load()
+
+	assert.ErrorIs(t, err, ErrSpecNotFound)
+}
+
+func TestSpecLoad_Invalid(t *testing.T) {
+	fsys := newTestFS(t)
+	fsys.WriteFile(testPreset.GetSpecFilename(), []byte("deps: {"))
+	sut := NewSpec(testPreset).WithFS(fsys)
+
+	err := sut.Load()
+
+	assert.ErrorIs(t, err, ErrSpecInvalid)
+}
@@ -0,0 +1,303 @@
+package vending
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alevinval/vendor-go/internal/glob"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity of a Diagnostic.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Codes that identify the kind of problem a Diagnostic reports.
+const (
+	DiagSyntax              = "syntax"
+	DiagUnknownPreset       = "unknown-preset"
+	DiagMissingURL          = "missing-url"
+	DiagMissingRevision     = "missing-revision"
+	DiagInvalidRef          = "invalid-ref"
+	DiagDuplicateDependency = "duplicate-dependency"
+	DiagInvalidPath         = "invalid-path"
+	DiagInvalidPattern      = "invalid-pattern"
+	DiagOverlappingOutput   = "overlapping-output"
+	DiagInvalidPin          = "invalid-pin"
+)
+
+// Diagnostic is a problem found when validating a spec. Line and Column point
+// to the position in the YAML document where the problem was found, they are
+// zero when the position is unknown.
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Line     int
+	Column   int
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s [%s]", d.Line, d.Column, d.Severity, d.Message, d.Code)
+}
+
+// Diagnostics is the list of problems found when validating a spec.
+type Diagnostics []Diagnostic
+
+// HasErrors returns whether any of the diagnostics is an error.
+func (ds Diagnostics) HasErrors() bool {
+	return ds.Count(SeverityError) > 0
+}
+
+// Count returns the number of diagnostics with the given severity.
+func (ds Diagnostics) Count(severity Severity) int {
+	n := 0
+	for _, d := range ds {
+		if d.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// ValidateSpec reads the spec file of the preset from fsys, and checks it for
+// mistakes that would otherwise only show up once dependencies are installed.
+// An error is returned when the spec cannot be read, problems in its contents
+// are reported as Diagnostics.
+func ValidateSpec(fsys FS, preset Preset) (Diagnostics, error) {
+	preset = checkPreset(preset, false)
+
+	data, err := fsys.ReadFile(preset.GetSpecFilename())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %w", ErrSpecNotFound, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read file: %w", err)
+	}
+	return validateSpec(data, preset), nil
+}
+
+type validator struct {
+	preset Preset
+	diags  Diagnostics
+}
+
+var yamlErrLine = regexp.MustCompile(`line (\d+)`)
+
+func validateSpec(data []byte, preset Preset) Diagnostics {
+	v := &validator{preset: preset, diags: Diagnostics{}}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		v.syntaxError(err)
+		return v.diags
+	}
+
+	if _, err := applyMigrations(doc, specMigration); err != nil {
+		v.add(SeverityError, DiagSyntax, doc, "%s", err)
+		return v.diags
+	}
+
+	root := documentRoot(doc)
+	if root == nil {
+		v.add(SeverityError, DiagSyntax, doc, "spec must be a mapping")
+		return v.diags
+	}
+
+	spec := &Spec{Filters: NewFilters()}
+	if err := root.Decode(spec); err != nil {
+		v.syntaxError(err)
+		return v.diags
+	}
+
+	v.checkPreset(root, spec)
+	v.checkPatterns(root)
+
+	depNodes := []*yaml.Node{}
+	if deps := mappingValue(root, "deps"); deps != nil && deps.Kind == yaml.SequenceNode {
+		depNodes = deps.Content
+	}
+	for i, dep := range spec.Deps {
+		if i < len(depNodes) {
+			v.checkDependency(depNodes[i], dep)
+		}
+	}
+	v.checkDuplicates(depNodes, spec)
+	v.checkOverlaps(depNodes, spec)
+
+	return v.diags
+}
+
+func (v *validator) checkPreset(root *yaml.Node, spec *Spec) {
+	if spec.PresetName == "" || spec.PresetName == v.preset.GetPresetName() {
+		return
+	}
+	v.add(SeverityError, DiagUnknownPreset, mappingValue(root, "preset"),
+		"unknown preset %q, this tool uses the %q preset", spec.PresetName, v.preset.GetPresetName())
+}
+
+func (v *validator) checkDependency(node *yaml.Node, dep *Dependency) {
+	if dep.URL == "" {
+		v.add(SeverityError, DiagMissingURL, node, "dependency has no url")
+	}
+
+	if dep.Revision() == "" {
+		v.add(SeverityError, DiagMissingRevision, valueOr(node, "branch"),
+			"dependency %s has no branch nor ref", dep.ID())
+	} else if IsConstraint(dep.Revision()) {
+		if _, err := ParseConstraint(dep.Revision()); err != nil {
+			v.add(SeverityError, DiagInvalidRef, valueOr(node, "ref", "branch"), "%s", err)
+		}
+	}
+
+	if dep.Dest != "" && !filepath.IsLocal(dep.Dest) {
+		v.add(SeverityError, DiagInvalidPath, valueOr(node, "dest"),
+			"dest %q must be a relative path inside the vendor directory", dep.Dest)
+	}
+	if dep.Strip != "" && !filepath.IsLocal(dep.Strip) {
+		v.add(SeverityError, DiagInvalidPath, valueOr(node, "strip"),
+			"strip %q must be a relative path inside the repository", dep.Strip)
+	}
+
+	if _, _, err := dep.Pin.UntilDate(); err != nil {
+		v.add(SeverityError, DiagInvalidPin, valueOr(mappingValue(node, "pin"), "until"), "%s", err)
+	}
+	if dep.Pin != nil && !dep.Pinned {
+		v.add(SeverityWarning, DiagInvalidPin, valueOr(node, "pin"),
+			"dependency %s has a pin, but it is not pinned", dep.ID())
+	}
+
+	v.checkPatterns(node)
+}
+
+// checkPatterns validates the targets and ignores of the spec, or dependency,
+// mapping node.
+func (v *validator) checkPatterns(node *yaml.Node) {
+	for _, key := range []string{"targets", "ignores"} {
+		list := mappingValue(node, key)
+		if list == nil || list.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, item := range list.Content {
+			pattern := item.Value
+			switch {
+			case pattern == "":
+				v.add(SeverityError, DiagInvalidPath, item, "empty path in %s", key)
+			case strings.HasPrefix(pattern, "/") || !filepath.IsLocal(filepath.Clean(pattern)):
+				v.add(SeverityError, DiagInvalidPath, item,
+					"%q in %s must be relative to the repository root", pattern, key)
+			default:
+				if err := glob.Validate(pattern); err != nil {
+					v.add(SeverityError, DiagInvalidPattern, item, "%s", err)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) checkDuplicates(nodes []*yaml.Node, spec *Spec) {
+	seen := map[string]bool{}
+	for i, dep := range spec.Deps {
+		id := strings.ToLower(dep.ID())
+		if id == "" || i >= len(nodes) {
+			continue
+		}
+		if seen[id] {
+			v.add(SeverityError, DiagDuplicateDependency, valueOr(nodes[i], "name", "url"),
+				"dependency %s is declared more than once, use name to vendor the same url twice", dep.ID())
+		}
+		seen[id] = true
+	}
+}
+
+// checkOverlaps warns when two dependencies with the same priority copy into
+// the same destination with targets that overlap, since then both can vendor
+// different files into the same path.
+func (v *validator) checkOverlaps(nodes []*yaml.Node, spec *Spec) {
+	for j := range spec.Deps {
+		for i := 0; i < j && j < len(nodes); i++ {
+			a, b := spec.Deps[i], spec.Deps[j]
+			if filepath.Clean(a.Dest) != filepath.Clean(b.Dest) || filepath.Clean(a.Strip) != filepath.Clean(b.Strip) {
+				continue
+			}
+			if a.Priority != b.Priority {
+				continue
+			}
+			if !targetsOverlap(effectiveTargets(spec, a), effectiveTargets(spec, b)) {
+				continue
+			}
+			v.add(SeverityWarning, DiagOverlappingOutput, nodes[j],
+				"dependencies %s and %s may write the same output paths, consider using dest or priority", a.ID(), b.ID())
+		}
+	}
+}
+
+func (v *validator) syntaxError(err error) {
+	line := 0
+	if m := yamlErrLine.FindStringSubmatch(err.Error()); m != nil {
+		line, _ = strconv.Atoi(m[1])
+	}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		err = errors.New(typeErr.Errors[0])
+	}
+	v.diags = append(v.diags, Diagnostic{
+		Severity: SeverityError,
+		Code:     DiagSyntax,
+		Message:  err.Error(),
+		Line:     line,
+	})
+}
+
+func (v *validator) add(severity Severity, code string, node *yaml.Node, format string, args ...interface{}) {
+	d := Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if node != nil {
+		d.Line, d.Column = node.Line, node.Column
+	}
+	v.diags = append(v.diags, d)
+}
+
+// valueOr returns the value node of the first key that is present in the
+// mapping, or the mapping itself when none is.
+func valueOr(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if value := mappingValue(node, key); value != nil {
+			return value
+		}
+	}
+	return node
+}
+
+func effectiveTargets(spec *Spec, dep *Dependency) []string {
+	targets := append([]string{}, spec.Filters.Targets...)
+	if dep.Filters != nil {
+		targets = append(targets, dep.Filters.Targets...)
+	}
+	return targets
+}
+
+func targetsOverlap(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, pa := range a {
+		for _, pb := range b {
+			if glob.MatchWithin(pa, pb) || glob.MatchWithin(pb, pa) {
+				return true
+			}
+		}
+	}
+	return false
+}
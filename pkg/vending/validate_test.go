@@ -0,0 +1,140 @@
+package vending
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSpec_WhenValid_HasNoDiagnostics(t *testing.T) {
+	diags := validateSpec([]byte(`version: v0.5.1
+preset: test-preset
+targets:
+  - "**/*.proto"
+deps:
+  - url: some-url
+    branch: master
+    targets:
+      - pkg/proto
+  - url: other-url
+    ref: ^1.4
+    dest: other
+`), testPreset)
+
+	assert.Empty(t, diags)
+	assert.False(t, diags.HasErrors())
+}
+
+func TestValidateSpec_ChecksPins(t *testing.T) {
+	diags := validateSpec([]byte(`deps:
+  - url: some-url
+    branch: master
+    pinned: true
+    pin:
+      reason: some-reason
+      until: 2026-03-09
+  - url: other-url
+    branch: master
+    pinned: true
+    pin:
+      until: next week
+  - url: another-url
+    branch: master
+    pin:
+      reason: some-reason
+`), testPreset)
+
+	pins := Diagnostics{}
+	for _, d := range diags {
+		if d.Code == DiagInvalidPin {
+			pins = append(pins, d)
+		}
+	}
+	assert.Len(t, pins, 2)
+	assert.Equal(t, SeverityError, pins[0].Severity)
+	assert.Equal(t, 12, pins[0].Line)
+	assert.Equal(t, SeverityWarning, pins[1].Severity)
+}
+
+func TestValidateSpec_ReportsPositions(t *testing.T) {
+	diags := validateSpec([]byte(`version: v0.5.1
+preset: unknown
+deps:
+  - url: some-url
+    branch: ""
+    targets:
+      - /absolute
+      - ../outside
+      - "api/[a-"
+  - url: SOME-URL
+    branch: master
+    dest: ../escape
+    strip: /root
+  - url: other-url
+    ref: ^x
+    dest: other
+`), testPreset)
+
+	assert.True(t, diags.HasErrors())
+	assert.Equal(t, Diagnostics{
+		{SeverityError, DiagUnknownPreset, `unknown preset "unknown", this tool uses the "test-preset" preset`, 2, 9},
+		{SeverityError, DiagMissingRevision, "dependency some-url has no branch nor ref", 5, 13},
+		{SeverityError, DiagInvalidPath, `"/absolute" in targets must be relative to the repository root`, 7, 9},
+		{SeverityError, DiagInvalidPath, `"../outside" in targets must be relative to the repository root`, 8, 9},
+		{SeverityError, DiagInvalidPattern, `invalid pattern "api/[a-": syntax error in pattern`, 9, 9},
+		{SeverityError, DiagInvalidPath, `dest "../escape" must be a relative path inside the vendor directory`, 12, 11},
+		{SeverityError, DiagInvalidPath, `strip "/root" must be a relative path inside the repository`, 13, 12},
+		{SeverityError, DiagInvalidRef, `invalid constraint "^x": invalid version "x"`, 15, 10},
+		{SeverityError, DiagDuplicateDependency, "dependency SOME-URL is declared more than once, use name to vendor the same url twice", 10, 10},
+	}, diags)
+}
+
+func TestValidateSpec_WarnsOnOverlappingOutputs(t *testing.T) {
+	diags := validateSpec([]byte(`deps:
+  - url: some-url
+    branch: master
+    targets:
+      - docs
+  - url: other-url
+    branch: master
+    targets:
+      - docs/api
+  - url: third-url
+    branch: master
+    targets:
+      - docs
+    dest: third
+  - url: fourth-url
+    branch: master
+    targets:
+      - docs
+    priority: 1
+`), testPreset)
+
+	assert.False(t, diags.HasErrors())
+	assert.Equal(t, 1, diags.Count(SeverityWarning))
+	assert.Equal(t, DiagOverlappingOutput, diags[0].Code)
+	assert.Equal(t, 6, diags[0].Line)
+}
+
+func TestValidateSpec_ReportsSyntaxErrors(t *testing.T) {
+	diags := validateSpec([]byte("deps:\n  - url: [unclosed\n"), testPreset)
+
+	assert.Len(t, diags, 1)
+	assert.Equal(t, DiagSyntax, diags[0].Code)
+	assert.NotZero(t, diags[0].Line)
+}
+
+func TestValidateSpec_ReadsPresetFile(t *testing.T) {
+	fsys := newTestFS(t)
+
+	_, err := ValidateSpec(fsys, testPreset)
+	assert.Error(t, err)
+
+	err = fsys.WriteFile(testPreset.GetSpecFilename(), []byte("deps:\n  - url: some-url\n"))
+	assert.NoError(t, err)
+
+	diags, err := ValidateSpec(fsys, testPreset)
+	assert.NoError(t, err)
+	assert.Equal(t, DiagMissingRevision, diags[0].Code)
+}
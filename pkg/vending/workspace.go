@@ -0,0 +1,81 @@
+package vending
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WorkspaceFilename is the name of the file that declares a workspace, at the
+// root of a monorepo.
+const WorkspaceFilename = "vending.work.yml"
+
+// Workspace groups the projects of a monorepo, each with its own spec, so all
+// of them can be installed at once.
+//
+// This model directly maps to the serialized YAML of the workspace file.
+type Workspace struct {
+	Version string `yaml:"version"`
+
+	// Members are the directories of the projects, relative to the
+	// workspace file.
+	Members []string `yaml:"members"`
+}
+
+// LoadWorkspace reads the workspace file from fsys, and checks its members.
+func LoadWorkspace(fsys FS) (*Workspace, error) {
+	data, err := fsys.ReadFile(WorkspaceFilename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %w", ErrWorkspaceNotFound, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read file: %w", err)
+	}
+
+	ws := &Workspace{}
+	if err := yaml.Unmarshal(data, ws); err != nil {
+		return nil, fmt.Errorf("%w: %s: cannot unmarshal: %w", ErrSpecInvalid, WorkspaceFilename, err)
+	}
+
+	seen := map[string]bool{}
+	for _, member := range ws.Members {
+		clean := filepath.Clean(member)
+		if !filepath.IsLocal(clean) {
+			return nil, fmt.Errorf("%w: %s: member %q must be a relative path inside the workspace", ErrSpecInvalid, WorkspaceFilename, member)
+		}
+		if seen[clean] {
+			return nil, fmt.Errorf("%w: %s: member %q is listed more than once", ErrSpecInvalid, WorkspaceFilename, member)
+		}
+		seen[clean] = true
+	}
+	return ws, nil
+}
+
+// FindDir looks for filename in dir, and then in each of its parents, the
+// same way git looks for the .git directory. It returns the directory where
+// the file was found: dir as it was given when the file is in dir, or an
+// absolute path when the file is in one of its parents.
+func FindDir(dir, filename string) (string, bool) {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(current, filename)); err == nil {
+			if start, _ := filepath.Abs(dir); start == current {
+				return dir, true
+			}
+			return current, true
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", false
+		}
+		current = parent
+	}
+}
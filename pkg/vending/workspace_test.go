@@ -0,0 +1,59 @@
+package vending
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadWorkspace(t *testing.T) {
+	fsys := newTestFS(t)
+	err := fsys.WriteFile(WorkspaceFilename, []byte("members:\n  - services/ledger\n  - services/billing/\n"))
+	assert.NoError(t, err)
+
+	sut, err := LoadWorkspace(fsys)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"services/ledger", "services/billing/"}, sut.Members)
+}
+
+func TestLoadWorkspace_NotFound(t *testing.T) {
+	_, err := LoadWorkspace(newTestFS(t))
+
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+}
+
+func TestLoadWorkspace_InvalidMembers(t *testing.T) {
+	for _, members := range []string{
+		"members:\n  - ../other-repo\n",
+		"members:\n  - /abs/path\n",
+		"members:\n  - some-dir\n  - ./some-dir\n",
+	} {
+		fsys := newTestFS(t)
+		assert.NoError(t, fsys.WriteFile(WorkspaceFilename, []byte(members)))
+
+		_, err := LoadWorkspace(fsys)
+
+		assert.ErrorIs(t, err, ErrSpecInvalid, members)
+	}
+}
+
+func TestFindDir(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	assert.NoError(t, os.MkdirAll(nested, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "some-file"), nil, os.ModePerm))
+
+	dir, ok := FindDir(nested, "some-file")
+	assert.True(t, ok)
+	assert.Equal(t, root, dir)
+
+	dir, ok = FindDir(root, "some-file")
+	assert.True(t, ok)
+	assert.Equal(t, root, dir)
+
+	_, ok = FindDir(nested, "other-file")
+	assert.False(t, ok)
+}
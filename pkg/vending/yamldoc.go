@@ -0,0 +1,230 @@
+package vending
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// blankMarker is a comment that temporarily stands for a blank line, since the
+// YAML encoder does not keep the blank lines of the documents it parses.
+const blankMarker = "#vending:blank-line"
+
+// mergeYaml converts obj to YAML, reusing the document in src when possible.
+// Nodes of src whose values did not change are kept as they are, so comments,
+// key order, quoting styles and blank lines survive. When src is empty or it
+// cannot be parsed, obj is converted from scratch.
+func mergeYaml(src []byte, obj interface{}) ([]byte, error) {
+	doc := &yaml.Node{}
+	if len(bytes.TrimSpace(src)) == 0 || yaml.Unmarshal(src, doc) != nil || documentRoot(doc) == nil {
+		return toYaml(obj)
+	}
+
+	updated := &yaml.Node{}
+	if err := updated.Encode(obj); err != nil {
+		return nil, err
+	}
+
+	markBlankLines(doc, strings.Split(string(src), "\n"))
+	root := documentRoot(doc)
+	*root = *mergeNode(root, updated)
+
+	data, err := toYaml(doc)
+	if err != nil {
+		return nil, err
+	}
+	return unmarkBlankLines(data), nil
+}
+
+// mergeNode returns the old node updated with the contents of the new one.
+func mergeNode(old, updated *yaml.Node) *yaml.Node {
+	if old.Kind != updated.Kind {
+		updated.HeadComment = old.HeadComment
+		updated.LineComment = old.LineComment
+		updated.FootComment = old.FootComment
+		return updated
+	}
+
+	switch old.Kind {
+	case yaml.ScalarNode:
+		if old.Value != updated.Value || old.Tag != updated.Tag {
+			old.Value = updated.Value
+			old.Tag = updated.Tag
+			if updated.Style != 0 {
+				old.Style = updated.Style
+			}
+		}
+	case yaml.MappingNode:
+		old.Content = mergeMapping(old.Content, updated.Content)
+	case yaml.SequenceNode:
+		old.Content = mergeSequence(old.Content, updated.Content)
+	default:
+		return updated
+	}
+	return old
+}
+
+// mergeMapping keeps the keys of the old mapping in their original order,
+// drops the keys that are gone, and inserts the new keys right after the key
+// that precedes them in the updated mapping.
+func mergeMapping(old, updated []*yaml.Node) []*yaml.Node {
+	oldIndex := map[string]int{}
+	for i := 0; i+1 < len(old); i += 2 {
+		oldIndex[old[i].Value] = i
+	}
+
+	keep := map[string]bool{}
+	for i := 0; i+1 < len(updated); i += 2 {
+		keep[updated[i].Value] = true
+	}
+
+	merged := []*yaml.Node{}
+	for i := 0; i+1 < len(old); i += 2 {
+		if keep[old[i].Value] {
+			merged = append(merged, old[i], old[i+1])
+		}
+	}
+
+	for i := 0; i+1 < len(updated); i += 2 {
+		key, value := updated[i], updated[i+1]
+		if j, ok := oldIndex[key.Value]; ok {
+			merged[indexOfKey(merged, old[j].Value)+1] = mergeNode(old[j+1], value)
+			continue
+		}
+
+		at := 0
+		if i > 0 {
+			at = indexOfKey(merged, updated[i-2].Value) + 2
+		}
+		merged = append(merged[:at], append([]*yaml.Node{key, value}, merged[at:]...)...)
+	}
+	return merged
+}
+
+// mergeSequence follows the order of the updated sequence, reusing the old
+// items that have the same identity: the value for scalars, and the name or
+// url for mappings.
+func mergeSequence(old, updated []*yaml.Node) []*yaml.Node {
+	used := make([]bool, len(old))
+	merged := make([]*yaml.Node, 0, len(updated))
+
+	for i, item := range updated {
+		match := -1
+		id, ok := nodeIdentity(item)
+		for j, candidate := range old {
+			if used[j] {
+				continue
+			}
+			if candidateID, _ := nodeIdentity(candidate); ok && strings.EqualFold(candidateID, id) {
+				match = j
+				break
+			}
+		}
+		if match < 0 && !ok && i < len(old) && !used[i] {
+			match = i
+		}
+
+		if match < 0 {
+			merged = append(merged, item)
+			continue
+		}
+		used[match] = true
+		merged = append(merged, mergeNode(old[match], item))
+	}
+	return merged
+}
+
+func nodeIdentity(node *yaml.Node) (string, bool) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value, true
+	case yaml.MappingNode:
+		if name := mappingValue(node, "name"); name != nil {
+			return name.Value, true
+		}
+		if url := mappingValue(node, "url"); url != nil {
+			return url.Value, true
+		}
+	}
+	return "", false
+}
+
+func indexOfKey(content []*yaml.Node, key string) int {
+	for i := 0; i+1 < len(content); i += 2 {
+		if content[i].Value == key {
+			return i
+		}
+	}
+	return -2
+}
+
+// markBlankLines adds a blankMarker head comment to the mapping keys and
+// sequence items that are preceded by a blank line in the source.
+func markBlankLines(node *yaml.Node, lines []string) {
+	mark := func(n *yaml.Node) {
+		start := n.Line - 1
+		if n.HeadComment != "" {
+			start -= strings.Count(n.HeadComment, "\n") + 1
+		}
+		if start > 0 && start <= len(lines) && strings.TrimSpace(lines[start-1]) == "" {
+			n.HeadComment = strings.TrimSuffix(blankMarker+"\n"+n.HeadComment, "\n")
+		}
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				mark(node.Content[i])
+			}
+			markBlankLines(node.Content[i+1], lines)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if i > 0 {
+				mark(item)
+			}
+			markBlankLines(item, lines)
+		}
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			markBlankLines(child, lines)
+		}
+	}
+}
+
+func unmarkBlankLines(data []byte) []byte {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == blankMarker {
+			lines[i] = ""
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}